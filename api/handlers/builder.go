@@ -5,6 +5,7 @@ import (
 	"hireforwork-server/db"
 	"hireforwork-server/service"
 	modules "hireforwork-server/service/modules"
+	"hireforwork-server/service/modules/audit"
 	"hireforwork-server/service/modules/auth"
 	"hireforwork-server/service/modules/jobs"
 	"net/http"
@@ -46,6 +47,8 @@ type HandlerConfig struct {
 	RequiresAuth     bool
 	FallbackCreate   func(*db.DB) interface{}
 	AdditionalFields map[string]func(*db.DB) interface{}
+	// ContainerFields injects shared services from the container, field name -> service name
+	ContainerFields map[string]string
 }
 
 var handlerConfigs = map[string]HandlerConfig{
@@ -53,6 +56,11 @@ var handlerConfigs = map[string]HandlerConfig{
 		HandlerType: reflect.TypeOf(&handlers.JobHandler{}),
 		ServiceName: "job",
 		ServiceType: reflect.TypeOf(&jobs.JobService{}),
+		AdditionalFields: map[string]func(*db.DB) interface{}{
+			"ReportService": func(db *db.DB) interface{} {
+				return modules.NewReportService(db)
+			},
+		},
 	},
 	"company": {
 		HandlerType:  reflect.TypeOf(&handlers.CompanyHandler{}),
@@ -63,6 +71,15 @@ var handlerConfigs = map[string]HandlerConfig{
 			"LoginStrategy": func(db *db.DB) interface{} {
 				return auth.NewCompanyLoginStrategy(auth.NewAuthService(db))
 			},
+			"VerificationService": func(db *db.DB) interface{} {
+				return modules.NewVerificationService(db)
+			},
+			"CompanyMemberService": func(db *db.DB) interface{} {
+				return modules.NewCompanyMemberService(db)
+			},
+			"ReportService": func(db *db.DB) interface{} {
+				return modules.NewReportService(db)
+			},
 		},
 	},
 	"career": {
@@ -71,9 +88,18 @@ var handlerConfigs = map[string]HandlerConfig{
 		ServiceType:  reflect.TypeOf(&modules.UserService{}),
 		RequiresAuth: true,
 		AdditionalFields: map[string]func(*db.DB) interface{}{
-			"LoginStrategy": func(db *db.DB) interface{} {
+			"CareerLoginStrategy": func(db *db.DB) interface{} {
 				return auth.NewCareerLoginStrategy(auth.NewAuthService(db))
 			},
+			"OIDCLoginStrategy": func(db *db.DB) interface{} {
+				return auth.NewOIDCLoginStrategy(auth.NewAuthService(db))
+			},
+			"VerificationService": func(db *db.DB) interface{} {
+				return modules.NewVerificationService(db)
+			},
+		},
+		ContainerFields: map[string]string{
+			"JobService": "job",
 		},
 	},
 	"tech": {
@@ -87,6 +113,25 @@ var handlerConfigs = map[string]HandlerConfig{
 		ServiceName: "field",
 		ServiceType: reflect.TypeOf(&modules.FieldService{}),
 	},
+	"auth": {
+		HandlerType:    reflect.TypeOf(&handlers.AuthHandler{}),
+		ServiceName:    "auth",
+		ServiceType:    reflect.TypeOf(&auth.AuthService{}),
+		FallbackCreate: func(db *db.DB) interface{} { return auth.NewAuthService(db) },
+	},
+	"admin": {
+		HandlerType:  reflect.TypeOf(&handlers.AdminHandler{}),
+		ServiceName:  "audit",
+		ServiceType:  reflect.TypeOf(&audit.Logger{}),
+		RequiresAuth: true,
+		ContainerFields: map[string]string{
+			"UserService":    "career",
+			"CompanyService": "company",
+			"JobService":     "job",
+			"ReportService":  "report",
+			"StaticService":  "static",
+		},
+	},
 	"category": {
 		HandlerType:    reflect.TypeOf(&handlers.CategoryHandler{}),
 		ServiceName:    "category",
@@ -133,6 +178,17 @@ func createHandler(config HandlerConfig, services *service.AppServices, db *db.D
 		}
 	}
 
+	// Inject services shared with the other handlers, e.g. the job service and its cache
+	if services != nil {
+		for fieldName, serviceName := range config.ContainerFields {
+			field := handlerValue.Elem().FieldByName(fieldName)
+			shared := services.GetService(serviceName)
+			if field.IsValid() && field.CanSet() && shared != nil && reflect.TypeOf(shared).AssignableTo(field.Type()) {
+				field.Set(reflect.ValueOf(shared))
+			}
+		}
+	}
+
 	// Inject additional fields
 	if config.AdditionalFields != nil {
 		for fieldName, creator := range config.AdditionalFields {
@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hireforwork-server/constants"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/middleware"
+	service "hireforwork-server/service/modules"
+	"hireforwork-server/service/modules/audit"
+	auth "hireforwork-server/service/modules/auth"
+	"hireforwork-server/service/modules/jobs"
+	"hireforwork-server/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminHandler serves the platform administration endpoints, every route is ADMIN only
+type AdminHandler struct {
+	AuditLogger    *audit.Logger
+	AuthService    *auth.AuthService
+	UserService    *service.UserService
+	CompanyService *service.CompanyService
+	JobService     *jobs.JobService
+	ReportService  *service.ReportService
+	StaticService  *service.StaticService
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	routes := map[string]map[string]http.HandlerFunc{
+		"GET": {
+			"/admin/audit-logs":        h.QueryAuditLogs,
+			"/admin/audit-logs/export": h.ExportAuditLogs,
+			"/admin/careers":           h.ListCareers,
+			"/admin/companies":         h.ListCompanies,
+			"/admin/reports":           h.ListReports,
+			"/admin/statistics":        h.GetStatistics,
+		},
+		"POST": {
+			"/admin/careers/" + vars["id"] + "/suspend":       h.SuspendCareer,
+			"/admin/careers/" + vars["id"] + "/restore":       h.RestoreCareer,
+			"/admin/careers/" + vars["id"] + "/impersonate":   h.ImpersonateCareer,
+			"/admin/companies/" + vars["id"] + "/suspend":     h.SuspendCompany,
+			"/admin/companies/" + vars["id"] + "/restore":     h.RestoreCompany,
+			"/admin/companies/" + vars["id"] + "/impersonate": h.ImpersonateCompany,
+			"/admin/jobs/" + vars["id"] + "/close":            h.CloseJob,
+			"/admin/reports/" + vars["id"] + "/resolve":       h.ResolveReport,
+		},
+		"DELETE": {
+			"/admin/jobs/" + vars["id"]: h.RemoveJob,
+		},
+	}
+
+	if handler, ok := routes[r.Method][r.URL.Path]; ok {
+		handler(w, r)
+		return
+	}
+
+	http.Error(w, "Not Found", http.StatusNotFound)
+}
+
+// moderationReason reads the optional reason of a moderation action and records it
+func moderationReason(r *http.Request) (string, error) {
+	var req interfaces.IModerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason != "" {
+		middleware.SetAuditDetail(r, "reason="+reason)
+	}
+	return reason, nil
+}
+
+func writeModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound), errors.Is(err, jobs.ErrJobNotFound), errors.Is(err, service.ErrReportNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, auth.ErrCannotImpersonate):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalidAccountState), errors.Is(err, service.ErrInvalidReportStatus), errors.Is(err, service.ErrReportTargetNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(value)
+}
+
+// ListCareers searches careers of any moderation status (status=ACTIVE|SUSPENDED|DELETED|ANY)
+func (h *AdminHandler) ListCareers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+
+	users, err := h.UserService.GetUser(
+		page,
+		pageSize,
+		query.Get("careerFirstName"),
+		query.Get("lastName"),
+		query.Get("careerEmail"),
+		query.Get("careerPhone"),
+		utils.ParseOptionalBool(query.Get("isVerified")),
+		query.Get("status"),
+	)
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	writeJSON(w, users)
+}
+
+// ListCompanies searches companies of any moderation status
+func (h *AdminHandler) ListCompanies(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+
+	filter := interfaces.ICompanyFilter{
+		CompanyName:  query.Get("companyName"),
+		CompanyEmail: query.Get("companyEmail"),
+		IsVerified:   utils.ParseOptionalBool(query.Get("isVerified")),
+		Status:       query.Get("status"),
+	}
+	companies, err := h.CompanyService.GetCompanies(page, pageSize, filter)
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	writeJSON(w, companies)
+}
+
+// SuspendCareer blocks the career and signs it out everywhere
+func (h *AdminHandler) SuspendCareer(w http.ResponseWriter, r *http.Request) {
+	reason, err := moderationReason(r)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	careerID := mux.Vars(r)["id"]
+	if err := h.UserService.SuspendUser(careerID, reason); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	if _id, err := primitive.ObjectIDFromHex(careerID); err == nil {
+		h.AuthService.RevokeAllSessions(_id)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) RestoreCareer(w http.ResponseWriter, r *http.Request) {
+	if err := h.UserService.RestoreUser(mux.Vars(r)["id"]); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SuspendCompany blocks the company, its members and API keys and signs them all out
+func (h *AdminHandler) SuspendCompany(w http.ResponseWriter, r *http.Request) {
+	reason, err := moderationReason(r)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	companyID := mux.Vars(r)["id"]
+	if err := h.CompanyService.SuspendCompany(companyID, reason); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	h.AuthService.RevokeCompanySessions(companyID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) RestoreCompany(w http.ResponseWriter, r *http.Request) {
+	if err := h.CompanyService.RestoreCompany(mux.Vars(r)["id"]); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ImpersonateCareer(w http.ResponseWriter, r *http.Request) {
+	h.impersonate(w, r, constants.CAREER)
+}
+
+func (h *AdminHandler) ImpersonateCompany(w http.ResponseWriter, r *http.Request) {
+	h.impersonate(w, r, constants.COMPANY)
+}
+
+// impersonate opens a support session as the account in {id}, support must say why
+func (h *AdminHandler) impersonate(w http.ResponseWriter, r *http.Request, accountType string) {
+	reason, err := moderationReason(r)
+	if err != nil || reason == "" {
+		http.Error(w, "Vui lòng cho biết lý do hỗ trợ", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.AuthService.Impersonate(middleware.GetClaims(r), accountType, mux.Vars(r)["id"], clientInfo(r))
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	writeJSON(w, response)
+}
+
+// CloseJob force-closes a job post, the company cannot reopen it
+func (h *AdminHandler) CloseJob(w http.ResponseWriter, r *http.Request) {
+	reason, err := moderationReason(r)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.JobService.ForceCloseJob(mux.Vars(r)["id"], reason); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveJob deletes a job post of any company
+func (h *AdminHandler) RemoveJob(w http.ResponseWriter, r *http.Request) {
+	if err := h.JobService.DeleteJob("", mux.Vars(r)["id"]); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListReports returns reported content, filter with status=OPEN to get the queue
+func (h *AdminHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+
+	reports, err := h.ReportService.ListReports(interfaces.IReportFilter{
+		Page:       page,
+		PageSize:   pageSize,
+		Status:     query.Get("status"),
+		TargetType: query.Get("targetType"),
+		TargetID:   query.Get("targetId"),
+	})
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	writeJSON(w, reports)
+}
+
+func (h *AdminHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.IReportResolve
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	middleware.SetAuditDetail(r, "status="+req.Status)
+
+	report, err := h.ReportService.ResolveReport(mux.Vars(r)["id"], middleware.GetUserID(r), req)
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	writeJSON(w, report)
+}
+
+// GetStatistics returns platform totals plus a time series,
+// ?from=2024-01-01&to=2024-03-31&interval=day|week (the last 30 days by default)
+func (h *AdminHandler) GetStatistics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	dashboard, err := h.StaticService.GetDashboard(query.Get("from"), query.Get("to"), query.Get("interval"))
+	if errors.Is(err, service.ErrInvalidStaticRange) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, dashboard)
+}
+
+func auditFilter(r *http.Request) interfaces.IAuditFilter {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+
+	return interfaces.IAuditFilter{
+		Page:       page,
+		PageSize:   pageSize,
+		ActorID:    query.Get("actorId"),
+		ActorRole:  query.Get("actorRole"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("targetType"),
+		TargetID:   query.Get("targetId"),
+		Outcome:    query.Get("outcome"),
+		IP:         query.Get("ip"),
+		CreateFrom: query.Get("createFrom"),
+		CreateTo:   query.Get("createTo"),
+	}
+}
+
+func (h *AdminHandler) QueryAuditLogs(w http.ResponseWriter, r *http.Request) {
+	result, err := h.AuditLogger.Query(auditFilter(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// ExportAuditLogs downloads the entries matching the filters as CSV
+func (h *AdminHandler) ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	filter := auditFilter(r)
+	middleware.SetAuditDetail(r, r.URL.RawQuery)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-log-%s.csv"`, time.Now().UTC().Format("20060102-150405")))
+	if err := h.AuditLogger.ExportCSV(filter, w); err != nil {
+		// headers are already sent, the truncated file is the only signal left
+		middleware.SetAuditDetail(r, r.URL.RawQuery+" error="+err.Error())
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/json"
+	auth "hireforwork-server/service/modules/auth"
+	"net/http"
+)
+
+// AuthHandler serves endpoints shared by every account type
+type AuthHandler struct {
+	AuthService *auth.AuthService
+}
+
+func (h *AuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/jwks.json":
+		h.GetJWKS(w, r)
+	default:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// GetJWKS publishes the public keys access tokens can be verified with
+func (h *AuthHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.AuthService.Keys.JWKS())
+}
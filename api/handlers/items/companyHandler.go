@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/interfaces"
+	"hireforwork-server/middleware"
 	"hireforwork-server/models"
 	service "hireforwork-server/service/modules"
 	auth "hireforwork-server/service/modules/auth"
+	"hireforwork-server/utils"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -18,15 +22,23 @@ import (
 )
 
 type CompanyHandler struct {
-	CompanyService *service.CompanyService
-	LoginStrategy  auth.LoginStrategy
+	CompanyService       *service.CompanyService
+	AuthService          *auth.AuthService
+	LoginStrategy        auth.LoginStrategy
+	VerificationService  *service.VerificationService
+	CompanyMemberService *service.CompanyMemberService
+	ReportService        *service.ReportService
 }
 
 func NewCompanyHandler(dbInstance *db.DB) *CompanyHandler {
 	authService := auth.NewAuthService(dbInstance)
 	return &CompanyHandler{
-		CompanyService: service.NewCompanyService(dbInstance),
-		LoginStrategy:  auth.NewCompanyLoginStrategy(authService),
+		CompanyService:       service.NewCompanyService(dbInstance),
+		AuthService:          authService,
+		LoginStrategy:        auth.NewCompanyLoginStrategy(authService),
+		VerificationService:  service.NewVerificationService(dbInstance),
+		CompanyMemberService: service.NewCompanyMemberService(dbInstance),
+		ReportService:        service.NewReportService(dbInstance),
 	}
 }
 
@@ -39,12 +51,20 @@ func (h *CompanyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			"/companies/random":       h.GetRandomCompanyHandler,
 			"/companies/{id}":         h.GetCompanyByID,
 			"/companies/get-job/{id}": h.GetJobsByCompany,
+			"/companies/verify-email": h.VerifyEmail,
 		},
 		"POST": {
 			"/companies/auth/login":           h.Login,
+			"/companies/auth/refresh":         h.RefreshToken,
+			"/companies/auth/mfa/verify":      h.mfa().Verify,
+			"/companies/mfa/enroll":           h.mfa().Enroll,
+			"/companies/mfa/confirm":          h.mfa().Confirm,
+			"/companies/auth/logout":          h.Logout,
 			"/companies/create":               h.CreateCompany,
+			"/companies/resend-verification":  h.ResendVerification,
 			"/request-password-reset-company": h.RequestPasswordCompanyResetHandler,
 			"/reset-password-company":         h.ResetPasswordCompanyHandler,
+			"/companies/members/accept":       h.AcceptInvitation,
 		},
 	}
 
@@ -53,15 +73,31 @@ func (h *CompanyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"GET": {
 			"/companies/" + vars["id"] + "/get-applier": h.GetCareerApply,
 			"/companies/" + vars["id"] + "/get-static":  h.GetStatics,
+			"/companies/members":                        h.ListMembers,
+			"/companies/" + vars["id"] + "/jobs":        h.GetJobsByCompany,
+			"/companies/api-keys":                       h.ListAPIKeys,
+			"/companies/sessions":                       h.sessions().List,
 		},
 		"POST": {
 			"/companies/" + vars["id"] + "/update":       h.UpdateCompanyByID,
 			"/companies/" + vars["id"] + "/upload-cover": h.UploadCompanyCover,
 			"/companies/" + vars["id"] + "/upload-img":   h.UploadCompanyIMG,
 			"/companies/change-application-status":       h.ChangeResumeStatusHandler,
+			"/companies/mfa/disable":                     h.mfa().Disable,
+			"/companies/" + vars["id"] + "/unlock":       h.UnlockAccount,
+			"/companies/members/invite":                  h.InviteMember,
+			"/companies/api-keys":                        h.CreateAPIKey,
+			"/companies/sessions/revoke-others":          h.sessions().RevokeOthers,
+			"/companies/" + vars["id"] + "/report":       h.reports().Create,
+		},
+		"PUT": {
+			"/companies/" + vars["id"]: h.UpdateCompanyByID,
 		},
 		"DELETE": {
-			"/companies/" + vars["id"]: h.DeleteCompanyByID,
+			"/companies/" + vars["id"]:          h.DeleteCompanyByID,
+			"/companies/members/" + vars["id"]:  h.RemoveMember,
+			"/companies/api-keys/" + vars["id"]: h.RevokeAPIKey,
+			"/companies/sessions/" + vars["id"]: h.sessions().Revoke,
 		},
 	}
 
@@ -92,8 +128,16 @@ func (h *CompanyHandler) GetCompaniesHandler(w http.ResponseWriter, r *http.Requ
 		StartDate:    h.getPointer(r.URL.Query().Get("startDate")),
 		EndDate:      h.getPointer(r.URL.Query().Get("endDate")),
 	}
+	if middleware.GetRole(r) == constants.ADMIN {
+		filter.IsVerified = utils.ParseOptionalBool(r.URL.Query().Get("isVerified"))
+		filter.Status = r.URL.Query().Get("status")
+	}
 
 	companies, err := h.CompanyService.GetCompanies(page, pageSize, filter)
+	if errors.Is(err, service.ErrInvalidAccountState) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting companies: %v", err)
 		http.Error(w, "Failed to get companies", http.StatusInternalServerError)
@@ -143,6 +187,10 @@ func (h *CompanyHandler) CreateCompany(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response, err := h.CompanyService.CreateCompany(company)
+	if errors.Is(err, service.ErrEmailTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -160,6 +208,10 @@ func (h *CompanyHandler) CreateCompany(w http.ResponseWriter, r *http.Request) {
 func (h *CompanyHandler) DeleteCompanyByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	response := h.CompanyService.DeleteCompanyByID(vars["id"])
+	// A deleted company and its members are signed out everywhere
+	if response.StatusCode == http.StatusAccepted {
+		h.AuthService.RevokeCompanySessions(vars["id"])
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(response.StatusCode)
 }
@@ -175,6 +227,10 @@ func (h *CompanyHandler) UpdateCompanyByID(w http.ResponseWriter, r *http.Reques
 	}
 
 	updatedCompany, err := h.CompanyService.UpdateCompanyByID(companyID, updatedData)
+	if errors.Is(err, service.ErrEmailTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -197,10 +253,13 @@ func (h *CompanyHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	credential.IP = utils.ClientIP(r)
+	credential.UserAgent = r.UserAgent()
+	middleware.SetAuditActor(r, credential.Username)
 	if credential.Role == "COMPANY" {
 		response, err := h.LoginStrategy.Login(credential)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeLoginError(w, err)
 			return
 		}
 
@@ -210,6 +269,194 @@ func (h *CompanyHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *CompanyHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if err := h.VerificationService.VerifyEmail(constants.COMPANY, r.URL.Query().Get("token")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified successfully"})
+}
+
+func (h *CompanyHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.VerificationService.ResendVerification(constants.COMPANY, req.Email, utils.ClientIP(r))
+	if errors.Is(err, service.ErrVerificationThrottled) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *CompanyHandler) mfa() mfaEndpoints {
+	return mfaEndpoints{authService: h.AuthService, accountType: constants.COMPANY}
+}
+
+func (h *CompanyHandler) sessions() sessionEndpoints {
+	return sessionEndpoints{authService: h.AuthService}
+}
+
+func (h *CompanyHandler) reports() reportEndpoints {
+	return reportEndpoints{reportService: h.ReportService, targetType: constants.REPORT_TARGET_COMPANY}
+}
+
+func (h *CompanyHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	if err := h.AuthService.UnlockAccount(constants.COMPANY, mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CompanyHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.CompanyMemberService.ListMembers(middleware.GetCompanyID(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+func (h *CompanyHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.IMemberInvite
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.CompanyMemberService.InviteMember(middleware.GetCompanyID(r), middleware.GetUserID(r), req)
+	if errors.Is(err, service.ErrMemberExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(member)
+}
+
+func (h *CompanyHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.IMemberAccept
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.CompanyMemberService.AcceptInvitation(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(member)
+}
+
+func (h *CompanyHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	err := h.CompanyMemberService.RemoveMember(middleware.GetCompanyID(r), mux.Vars(r)["id"], middleware.GetUserID(r))
+	if errors.Is(err, service.ErrMemberNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CompanyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	apiKeys, err := h.AuthService.ListAPIKeys(middleware.GetCompanyID(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiKeys)
+}
+
+func (h *CompanyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	apiKey, err := h.AuthService.CreateAPIKey(middleware.GetCompanyID(r), middleware.GetUserID(r), req.Name, req.Scopes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(apiKey)
+}
+
+func (h *CompanyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.AuthService.RevokeAPIKey(middleware.GetCompanyID(r), mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CompanyHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req auth.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.AuthService.Refresh(req.RefreshToken, constants.COMPANY, clientInfo(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *CompanyHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req auth.RefreshRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.AuthService.Logout(req.RefreshToken, middleware.GetSessionID(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *CompanyHandler) GetCareersByJobID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	jobID := vars["id"]
@@ -415,7 +662,16 @@ func (h *CompanyHandler) ChangeResumeStatusHandler(w http.ResponseWriter, r *htt
 		http.Error(w, "Vui lòng thử lại sau", http.StatusBadRequest)
 		return
 	}
-	err = h.CompanyService.ChangeResumeStatus(req.ResumeID, req.Status)
+	middleware.SetAuditTarget(r, req.ResumeID)
+	middleware.SetAuditDetail(r, "status="+req.Status)
+
+	// companies only change the status of applications to their own jobs
+	companyID, ok := jobScope(r)
+	if !ok {
+		http.Error(w, "Bạn không có quyền thực hiện thao tác này", http.StatusForbidden)
+		return
+	}
+	err = h.CompanyService.ChangeResumeStatus(companyID, req.ResumeID, req.Status)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -426,18 +682,24 @@ func (h *CompanyHandler) ChangeResumeStatusHandler(w http.ResponseWriter, r *htt
 }
 func (h *CompanyHandler) RequestPasswordCompanyResetHandler(w http.ResponseWriter, r *http.Request) {
 	var req interfaces.PasswordResetRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	middleware.SetAuditActor(r, req.Email)
 
-	code, err := h.CompanyService.RequestPasswordResetCompany(req.Email)
+	err := h.CompanyService.RequestPasswordResetCompany(req.Email, utils.ClientIP(r))
+	if errors.Is(err, service.ErrPasswordResetThrottled) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, "Có lỗi xảy ra, vui lòng thử lại sau", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"code": code})
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Nếu email tồn tại, mã xác nhận đã được gửi"})
 }
 
 func (h *CompanyHandler) ResetPasswordCompanyHandler(w http.ResponseWriter, r *http.Request) {
@@ -446,6 +708,7 @@ func (h *CompanyHandler) ResetPasswordCompanyHandler(w http.ResponseWriter, r *h
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	middleware.SetAuditActor(r, req.Email)
 
 	if err := h.CompanyService.ResetPasswordCompany(req.Email, req.Code, req.NewPassword); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -2,26 +2,34 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/interfaces"
 	"hireforwork-server/middleware"
 	"hireforwork-server/models"
+	service "hireforwork-server/service/modules"
 	"hireforwork-server/service/modules/jobs"
+	"hireforwork-server/utils"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type JobHandler struct {
-	JobService *jobs.JobService
+	JobService    *jobs.JobService
+	ReportService *service.ReportService
 }
 
 func NewJobHandler(dbInstance *db.DB) *JobHandler {
 	return &JobHandler{
-		JobService: jobs.NewJobService(dbInstance),
+		JobService:    jobs.NewJobService(dbInstance),
+		ReportService: service.NewReportService(dbInstance),
 	}
 }
 
@@ -60,29 +68,65 @@ func (h *JobHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				h.ApplyJob(w, r)
 				return
 			}
-		case strings.HasSuffix(path, "suggest"):
+		case strings.HasSuffix(path, "report"):
+			if r.Method == http.MethodPost {
+				reportEndpoints{reportService: h.ReportService, targetType: constants.REPORT_TARGET_JOB}.Create(w, r)
+				return
+			}
+		case strings.HasSuffix(path, "/status"):
+			if r.Method == http.MethodPost {
+				h.ChangeJobStatus(w, r)
+				return
+			}
+		case strings.HasSuffix(path, "/extend"):
 			if r.Method == http.MethodGet {
-				h.GetSuggestJobs(w, r)
-			} else {
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				h.ExtendJob(w, r)
+				return
 			}
 		default:
-			if _, ok := vars["id"]; ok && r.Method == http.MethodGet {
-				h.GetJobByID(w, r)
-			} else {
+			if _, ok := vars["id"]; !ok {
 				http.Error(w, "Not Found", http.StatusNotFound)
+				return
+			}
+			switch r.Method {
+			case http.MethodGet:
+				h.GetJobByID(w, r)
+			case http.MethodPut:
+				h.UpdateJobHandler(w, r)
+			case http.MethodDelete:
+				h.DeleteJobHandler(w, r)
+			default:
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			}
 		}
 	})
 	handlerFunc.ServeHTTP(w, r)
 }
 
-func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
-	pageStr := r.URL.Query().Get("page")
-	page, _ := strconv.Atoi(pageStr)
+// positiveQueryInt reads an optional positive integer query parameter, 0 when missing
+func positiveQueryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("Tham số %s không hợp lệ", name)
+	}
+	return number, nil
+}
 
-	pageSizeStr := r.URL.Query().Get("pageSize")
-	pageSize, _ := strconv.Atoi(pageSizeStr)
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	page, err := positiveQueryInt(r, "page")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageSize, err := positiveQueryInt(r, "pageSize")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	isHotStr := r.URL.Query().Get("isHot")
 	isHot := false
@@ -96,6 +140,14 @@ func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
 	salaryToStr := r.URL.Query().Get("salaryTo")
 	salaryTo, _ := strconv.ParseInt(salaryToStr, 10, 64)
 
+	radiusKm := 0.0
+	if radiusStr := r.URL.Query().Get("radiusKm"); radiusStr != "" {
+		if radiusKm, err = strconv.ParseFloat(radiusStr, 64); err != nil {
+			http.Error(w, jobs.ErrInvalidRadius.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	filter := interfaces.IJobFilter{
 		JobTitle:        r.URL.Query().Get("jobTitle"),
 		CompanyName:     r.URL.Query().Get("companyName"),
@@ -105,48 +157,156 @@ func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
 		EndDateTo:       r.URL.Query().Get("endDateTo"),
 		SalaryFrom:      salaryFrom,
 		SalaryTo:        salaryTo,
+		SalaryCurrency:  strings.ToUpper(r.URL.Query().Get("salaryCurrency")),
+		SalaryPeriod:    strings.ToUpper(r.URL.Query().Get("salaryPeriod")),
 		WorkingLocation: r.URL.Query()["workingLocation"],
 		JobRequirement:  r.URL.Query()["jobRequirement"],
 		JobCategory:     r.URL.Query()["jobCategory"],
+		JobTech:         r.URL.Query()["jobTech"],
+		WorkArrangement: r.URL.Query()["workArrangement"],
+		EmploymentType:  r.URL.Query()["employmentType"],
+		RemoteCountry:   r.URL.Query().Get("remoteCountry"),
 		JobLevel:        r.URL.Query().Get("jobLevel"),
 		IsHot:           isHot,
 		Query:           r.URL.Query().Get("query"),
+		Sort:            r.URL.Query().Get("sort"),
+		Cursor:          r.URL.Query().Get("cursor"),
+		Near:            r.URL.Query().Get("near"),
+		RadiusKm:        radiusKm,
 	}
 
-	jobs, err := h.JobService.GetJob(page, pageSize, filter)
+	jobList, err := h.JobService.GetJob(page, pageSize, filter)
+	if errors.Is(err, jobs.ErrInvalidJobSort) || errors.Is(err, jobs.ErrInvalidCursor) ||
+		errors.Is(err, jobs.ErrInvalidRadius) || errors.Is(err, jobs.ErrUnknownPlace) ||
+		errors.Is(err, jobs.ErrInvalidSalary) || errors.Is(err, jobs.ErrInvalidWorkArrangement) ||
+		errors.Is(err, jobs.ErrInvalidEmploymentType) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(jobs); err != nil {
+	if err := json.NewEncoder(w).Encode(jobList); err != nil {
 		http.Error(w, "Error encoding JSON", http.StatusInternalServerError)
 	}
 }
 
-func (h *JobHandler) CreateJobHandler(w http.ResponseWriter, r *http.Request) {
+// serverManagedJobFields can only be changed by the server, never by the payload
+var serverManagedJobFields = []string{"isDeleted", "createAt", "closedByAdmin", "closeReason", "publishedAt", "expiryNotifiedAt", "applicationCount", "viewCount"}
+
+// decodeJobPayload decodes a job body and rejects fields the client may not set
+func decodeJobPayload(r *http.Request) (models.Jobs, error) {
 	var job models.Jobs
-	err := json.NewDecoder(r.Body).Decode(&job)
+
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Lỗi khi tạo mới bài đăng"), http.StatusInternalServerError)
+		return job, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return job, err
+	}
+	for _, field := range serverManagedJobFields {
+		if _, ok := raw[field]; ok {
+			return job, fmt.Errorf("Không được phép cập nhật trường %s", field)
+		}
+	}
+
+	err = json.Unmarshal(body, &job)
+	return job, err
+}
+
+// jobViewer identifies who opens a job page. Companies and admins looking at jobs are
+// not counted. Anonymous visitors are told apart by their address only, anything they
+// send themselves could be changed on every request to count again.
+func jobViewer(r *http.Request) (jobs.JobViewer, bool) {
+	switch middleware.GetRole(r) {
+	case constants.CAREER:
+		return jobs.JobViewer{CareerID: middleware.GetUserID(r)}, true
+	case "":
+		return jobs.JobViewer{Session: utils.HashToken(utils.ClientIP(r))}, true
+	}
+	return jobs.JobViewer{}, false
+}
+
+// jobScope returns the company a job mutation is restricted to, empty for admins
+func jobScope(r *http.Request) (string, bool) {
+	if middleware.GetRole(r) == constants.ADMIN {
+		return "", true
+	}
+	companyID := middleware.GetCompanyID(r)
+	return companyID, companyID != ""
+}
+
+func (h *JobHandler) CreateJobHandler(w http.ResponseWriter, r *http.Request) {
+	companyID, ok := jobScope(r)
+	if !ok {
+		http.Error(w, "Bạn không có quyền thực hiện thao tác này", http.StatusForbidden)
+		return
+	}
+
+	job, err := decodeJobPayload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	createJob, err := h.JobService.CreateJob(companyID, job)
+	if errors.Is(err, jobs.ErrInvalidJobSchedule) || errors.Is(err, jobs.ErrInvalidJobTransition) ||
+		errors.Is(err, jobs.ErrInvalidSalary) || errors.Is(err, jobs.ErrInvalidWorkArrangement) ||
+		errors.Is(err, jobs.ErrInvalidEmploymentType) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	createJob, err := h.JobService.CreateJob(job)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Lỗi khi tạo mới bài đăng"), http.StatusInternalServerError)
 		return
 	}
+	middleware.SetAuditTarget(r, createJob.Id.Hex())
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createJob)
 }
 
 func (h *JobHandler) UpdateJobHandler(w http.ResponseWriter, r *http.Request) {
-	var job models.Jobs
-	err := json.NewDecoder(r.Body).Decode(&job)
-	updateJob, err := h.JobService.UpdateJob(job)
-	fmt.Println(updateJob)
+	companyID, ok := jobScope(r)
+	if !ok {
+		http.Error(w, "Bạn không có quyền thực hiện thao tác này", http.StatusForbidden)
+		return
+	}
+
+	job, err := decodeJobPayload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if id, ok := mux.Vars(r)["id"]; ok {
+		jobID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			http.Error(w, jobs.ErrJobNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		job.Id = jobID
+	}
+	middleware.SetAuditTarget(r, job.Id.Hex())
+
+	updateJob, err := h.JobService.UpdateJob(companyID, job)
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, jobs.ErrJobClosedByAdmin) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, jobs.ErrInvalidSalary) || errors.Is(err, jobs.ErrInvalidWorkArrangement) ||
+		errors.Is(err, jobs.ErrInvalidEmploymentType) || errors.Is(err, jobs.ErrInvalidJobSchedule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintln("Có lỗi xảy ra khi cập nhập!"), http.StatusInternalServerError)
 		return
@@ -159,6 +319,83 @@ func (h *JobHandler) UpdateJobHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *JobHandler) DeleteJobHandler(w http.ResponseWriter, r *http.Request) {
+	companyID, ok := jobScope(r)
+	if !ok {
+		http.Error(w, "Bạn không có quyền thực hiện thao tác này", http.StatusForbidden)
+		return
+	}
+
+	err := h.JobService.DeleteJob(companyID, mux.Vars(r)["id"])
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeJobStatus drafts, schedules, publishes, pauses, closes or archives a job
+func (h *JobHandler) ChangeJobStatus(w http.ResponseWriter, r *http.Request) {
+	companyID, ok := jobScope(r)
+	if !ok {
+		http.Error(w, "Bạn không có quyền thực hiện thao tác này", http.StatusForbidden)
+		return
+	}
+
+	var request interfaces.IJobStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	middleware.SetAuditDetail(r, "status="+request.Status)
+
+	job, err := h.JobService.ChangeStatus(companyID, mux.Vars(r)["id"], request)
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrJobClosedByAdmin), errors.Is(err, jobs.ErrInvalidJobTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, jobs.ErrInvalidJobSchedule):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ExtendJob redeems the one-click link sent with an expiry reminder
+func (h *JobHandler) ExtendJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.JobService.ExtendJob(r.URL.Query().Get("token"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, jobs.ErrInvalidExtendLink) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Gia hạn bài đăng thành công",
+		"expireDate": job.ExpireDate,
+		"status":     job.Status,
+	})
+}
+
 func (h *JobHandler) SaveJob(w http.ResponseWriter, r *http.Request) {
 	careerId := middleware.GetUserID(r)
 	jobId := mux.Vars(r)["id"]
@@ -185,27 +422,17 @@ func (h *JobHandler) ApplyJob(w http.ResponseWriter, r *http.Request) {
 	request.JobID = mux.Vars(r)["id"]
 
 	err := h.JobService.Apply(request)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if errors.Is(err, jobs.ErrJobNotOpen) {
+		http.Error(w, err.Error(), http.StatusConflict)
 		return
 	}
-
-	json.NewEncoder(w).Encode(map[string]string{"message": "Ứng tuyển thành công, vui lòng kiểm tra email!"})
-
-}
-
-func (h *JobHandler) GetSuggestJobs(w http.ResponseWriter, r *http.Request) {
-	jobs, err := h.JobService.GetLatestJobs()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(jobs); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Ứng tuyển thành công, vui lòng kiểm tra email!"})
+
 }
 
 func (h *JobHandler) GetJobByID(w http.ResponseWriter, r *http.Request) {
@@ -219,6 +446,9 @@ func (h *JobHandler) GetJobByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if viewer, ok := jobViewer(r); ok {
+		h.JobService.RecordView(vars["id"], viewer)
+	}
 
 	w.WriteHeader(http.StatusOK)
 
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/middleware"
+	auth "hireforwork-server/service/modules/auth"
+	"net/http"
+)
+
+// mfaEndpoints serves the TOTP endpoints shared by careers and companies
+type mfaEndpoints struct {
+	authService *auth.AuthService
+	accountType string
+}
+
+func accountTypeOf(role string) string {
+	if role == constants.COMPANY {
+		return constants.COMPANY
+	}
+	return constants.CAREER
+}
+
+// account returns who is enrolling: the signed-in account, or the account of an
+// enrollment token issued by a login blocked by the ADMIN MFA policy. API keys act as
+// the company owner but must never manage its second factor.
+func (m mfaEndpoints) account(r *http.Request, mfaToken string) (string, bool, error) {
+	claims := middleware.GetClaims(r)
+	if claims != nil && claims.APIKeyID != "" {
+		return "", false, errors.New("API key không được dùng để quản lý xác thực hai lớp")
+	}
+	// Support sessions cannot leave a second factor behind either
+	if claims != nil && claims.ImpersonatedBy != "" {
+		return "", false, errors.New("Phiên hỗ trợ không được thay đổi thông tin đăng nhập của tài khoản")
+	}
+	if claims != nil && accountTypeOf(middleware.GetRole(r)) == m.accountType {
+		return claims.Subject, false, nil
+	}
+	if mfaToken == "" {
+		return "", false, errors.New("Authentication required")
+	}
+	id, err := m.authService.ResolveEnrollmentToken(mfaToken, m.accountType)
+	return id, true, err
+}
+
+func (m mfaEndpoints) writeError(w http.ResponseWriter, err error) {
+	var locked *auth.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		writeLoginError(w, err)
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled), errors.Is(err, auth.ErrMFANotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, auth.ErrMFARequired):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	}
+}
+
+func (m mfaEndpoints) Verify(w http.ResponseWriter, r *http.Request) {
+	var req auth.MFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := m.authService.VerifyMFALogin(req.MFAToken, req.Code, m.accountType, clientInfo(r))
+	if err != nil {
+		m.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (m mfaEndpoints) Enroll(w http.ResponseWriter, r *http.Request) {
+	var req auth.MFARequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	accountID, _, err := m.account(r, req.MFAToken)
+	if err != nil {
+		m.writeError(w, err)
+		return
+	}
+
+	enrollment, err := m.authService.BeginMFAEnrollment(m.accountType, accountID)
+	if err != nil {
+		m.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (m mfaEndpoints) Confirm(w http.ResponseWriter, r *http.Request) {
+	var req auth.MFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	accountID, viaEnrollmentToken, err := m.account(r, req.MFAToken)
+	if err != nil {
+		m.writeError(w, err)
+		return
+	}
+	middleware.SetAuditTarget(r, accountID)
+
+	recoveryCodes, err := m.authService.ConfirmMFAEnrollment(m.accountType, accountID, req.Code)
+	if err != nil {
+		m.writeError(w, err)
+		return
+	}
+
+	confirmation := auth.MFAConfirmation{RecoveryCodes: recoveryCodes}
+	if viaEnrollmentToken {
+		login, err := m.authService.IssueTokensFor(m.accountType, accountID, clientInfo(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		confirmation.Login = &login
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(confirmation)
+}
+
+func (m mfaEndpoints) Disable(w http.ResponseWriter, r *http.Request) {
+	var req auth.MFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if err := m.authService.DisableMFA(m.accountType, middleware.GetUserID(r), req.Code); err != nil {
+		m.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/middleware"
+	service "hireforwork-server/service/modules"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// reportEndpoints lets careers flag the job or company in {id} for the moderators
+type reportEndpoints struct {
+	reportService *service.ReportService
+	targetType    string
+}
+
+func (e reportEndpoints) Create(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.IReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	report, err := e.reportService.CreateReport(middleware.GetUserID(r), e.targetType, mux.Vars(r)["id"], req)
+	switch {
+	case errors.Is(err, service.ErrReportTargetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, service.ErrReportExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, service.ErrInvalidReport):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
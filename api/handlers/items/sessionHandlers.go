@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"hireforwork-server/middleware"
+	auth "hireforwork-server/service/modules/auth"
+	"hireforwork-server/utils"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// clientInfo describes the device of the request for the session it opens or refreshes
+func clientInfo(r *http.Request) auth.ClientInfo {
+	return auth.ClientInfo{IP: utils.ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// sessionEndpoints lets careers and companies see and sign out their active sessions
+type sessionEndpoints struct {
+	authService *auth.AuthService
+}
+
+func (s sessionEndpoints) List(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.authService.ListSessions(middleware.GetUserID(r), middleware.GetSessionID(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (s sessionEndpoints) Revoke(w http.ResponseWriter, r *http.Request) {
+	err := s.authService.RevokeSession(middleware.GetUserID(r), mux.Vars(r)["id"])
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s sessionEndpoints) RevokeOthers(w http.ResponseWriter, r *http.Request) {
+	if err := s.authService.RevokeOtherSessions(middleware.GetUserID(r), middleware.GetSessionID(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
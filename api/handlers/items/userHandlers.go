@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/interfaces"
+	"hireforwork-server/middleware"
 	"hireforwork-server/models"
 	service "hireforwork-server/service/modules"
 	auth "hireforwork-server/service/modules/auth"
+	"hireforwork-server/service/modules/jobs"
 	"hireforwork-server/utils"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -31,14 +36,21 @@ var resumeAllowFile = map[string]bool{
 
 type UserHandler struct {
 	UserService         *service.UserService
+	AuthService         *auth.AuthService
 	CareerLoginStrategy auth.LoginStrategy
+	OIDCLoginStrategy   *auth.OIDCLoginStrategy
+	VerificationService *service.VerificationService
+	JobService          *jobs.JobService
 }
 
 func NewUserHandler(dbInstance *db.DB) *UserHandler {
 	authService := auth.NewAuthService(dbInstance)
 	return &UserHandler{
 		UserService:         service.NewUserService(dbInstance),
+		AuthService:         authService,
 		CareerLoginStrategy: auth.NewCareerLoginStrategy(authService),
+		OIDCLoginStrategy:   auth.NewOIDCLoginStrategy(authService),
+		VerificationService: service.NewVerificationService(dbInstance),
 	}
 }
 
@@ -51,6 +63,56 @@ func (h *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				h.Login(w, r)
 				return
 			}
+		case "/careers/auth/oidc/authorize":
+			if r.Method == http.MethodGet {
+				h.OIDCAuthorize(w, r)
+				return
+			}
+		case "/careers/auth/oidc/callback":
+			if r.Method == http.MethodGet || r.Method == http.MethodPost {
+				h.OIDCCallback(w, r)
+				return
+			}
+		case "/careers/auth/mfa/verify":
+			if r.Method == http.MethodPost {
+				h.mfa().Verify(w, r)
+				return
+			}
+		case "/careers/mfa/enroll":
+			if r.Method == http.MethodPost {
+				h.mfa().Enroll(w, r)
+				return
+			}
+		case "/careers/mfa/confirm":
+			if r.Method == http.MethodPost {
+				h.mfa().Confirm(w, r)
+				return
+			}
+		case "/careers/mfa/disable":
+			if r.Method == http.MethodPost {
+				h.mfa().Disable(w, r)
+				return
+			}
+		case "/careers/auth/refresh":
+			if r.Method == http.MethodPost {
+				h.RefreshToken(w, r)
+				return
+			}
+		case "/careers/auth/logout":
+			if r.Method == http.MethodPost {
+				h.Logout(w, r)
+				return
+			}
+		case "/careers/verify-email":
+			if r.Method == http.MethodGet {
+				h.VerifyEmail(w, r)
+				return
+			}
+		case "/careers/resend-verification":
+			if r.Method == http.MethodPost {
+				h.ResendVerification(w, r)
+				return
+			}
 		case "/careers/register":
 			if r.Method == http.MethodPost {
 				h.RegisterCareer(w, r)
@@ -100,6 +162,16 @@ func (h *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				h.GetAppliedJob(w, r)
 				return
 			}
+		case "/careers/" + vars["id"] + "/viewed-jobs":
+			if r.Method == http.MethodGet {
+				h.GetViewedJobs(w, r)
+				return
+			}
+		case "/careers/" + vars["id"] + "/recommended-jobs":
+			if r.Method == http.MethodGet {
+				h.GetRecommendedJobs(w, r)
+				return
+			}
 		case "/careers/" + vars["id"] + "/upload-image":
 			if r.Method == http.MethodPost {
 				h.UploadImage(w, r)
@@ -115,6 +187,26 @@ func (h *UserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				h.RemoveResume(w, r)
 				return
 			}
+		case "/careers/sessions":
+			if r.Method == http.MethodGet {
+				h.sessions().List(w, r)
+				return
+			}
+		case "/careers/sessions/revoke-others":
+			if r.Method == http.MethodPost {
+				h.sessions().RevokeOthers(w, r)
+				return
+			}
+		case "/careers/sessions/" + vars["id"]:
+			if r.Method == http.MethodDelete {
+				h.sessions().Revoke(w, r)
+				return
+			}
+		case "/careers/" + vars["id"] + "/unlock":
+			if r.Method == http.MethodPost {
+				h.UnlockAccount(w, r)
+				return
+			}
 		case "/careers/" + vars["id"] + "/update":
 			if r.Method == http.MethodPost {
 				h.UpdateUser(w, r)
@@ -143,8 +235,13 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	lastName := r.URL.Query().Get("lastName")
 	careerEmail := r.URL.Query().Get("careerEmail")
 	careerPhone := r.URL.Query().Get("careerPhone")
+	isVerified := utils.ParseOptionalBool(r.URL.Query().Get("isVerified"))
 
-	users, err := h.UserService.GetUser(page, pageSize, careerFirstName, lastName, careerEmail, careerPhone)
+	users, err := h.UserService.GetUser(page, pageSize, careerFirstName, lastName, careerEmail, careerPhone, isVerified, r.URL.Query().Get("status"))
+	if errors.Is(err, service.ErrInvalidAccountState) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -177,6 +274,10 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) DeleteUserByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	response := h.UserService.DeleteUserByID(vars["id"])
+	// A deleted career is signed out everywhere
+	if _id, err := primitive.ObjectIDFromHex(vars["id"]); err == nil && response.StatusCode == http.StatusAccepted {
+		h.AuthService.RevokeAllSessions(_id)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(response.StatusCode)
 }
@@ -278,11 +379,15 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	err := json.NewDecoder(r.Body).Decode(&credential)
 	if err != nil {
 		http.Error(w, "Invaild request", http.StatusBadRequest)
+		return
 	}
+	credential.IP = utils.ClientIP(r)
+	credential.UserAgent = r.UserAgent()
+	middleware.SetAuditActor(r, credential.Username)
 	if credential.Role == "CAREER" {
 		response, err := h.CareerLoginStrategy.Login(credential)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			writeLoginError(w, err)
 			return
 		}
 
@@ -293,6 +398,128 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// OIDCAuthorize returns the provider URL the frontend sends the user to, the browser
+// gets the state cookie the callback is checked against
+func (h *UserHandler) OIDCAuthorize(w http.ResponseWriter, r *http.Request) {
+	if h.OIDCLoginStrategy == nil {
+		http.Error(w, auth.ErrOIDCNotConfigured.Error(), http.StatusNotFound)
+		return
+	}
+
+	authorizationURL, state, err := h.OIDCLoginStrategy.AuthorizationURL()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	http.SetCookie(w, h.OIDCLoginStrategy.StateCookie(state))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"authorizationURL": authorizationURL})
+}
+
+// OIDCCallback signs the career in with the code and state returned by the provider,
+// either as the redirect itself (query) or posted by the frontend (body)
+func (h *UserHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.OIDCLoginStrategy == nil {
+		http.Error(w, auth.ErrOIDCNotConfigured.Error(), http.StatusNotFound)
+		return
+	}
+
+	credential := auth.Credentials{
+		Code:  r.URL.Query().Get("code"),
+		State: r.URL.Query().Get("state"),
+	}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
+			http.Error(w, "Invaild request", http.StatusBadRequest)
+			return
+		}
+	}
+	if providerError := r.URL.Query().Get("error"); providerError != "" {
+		http.Error(w, providerError, http.StatusUnauthorized)
+		return
+	}
+	// A callback URL started by someone else must not sign this browser in
+	http.SetCookie(w, h.OIDCLoginStrategy.ClearStateCookie())
+	if !h.OIDCLoginStrategy.StateMatchesCookie(r, credential.State) {
+		writeLoginError(w, auth.ErrInvalidOIDCState)
+		return
+	}
+	credential.IP = utils.ClientIP(r)
+	credential.UserAgent = r.UserAgent()
+
+	response, err := h.OIDCLoginStrategy.Login(credential)
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *UserHandler) sessions() sessionEndpoints {
+	return sessionEndpoints{authService: h.AuthService}
+}
+
+func (h *UserHandler) mfa() mfaEndpoints {
+	return mfaEndpoints{authService: h.AuthService, accountType: constants.CAREER}
+}
+
+// writeLoginError maps login failures to their status, locked clients get a Retry-After
+func writeLoginError(w http.ResponseWriter, err error) {
+	var locked *auth.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, auth.ErrEmailNotVerified), errors.Is(err, auth.ErrOIDCEmailNotVerified), errors.Is(err, auth.ErrOIDCLinkRefused), errors.Is(err, auth.ErrAccountSuspended):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	}
+}
+
+func (h *UserHandler) UnlockAccount(w http.ResponseWriter, r *http.Request) {
+	if err := h.AuthService.UnlockAccount(constants.CAREER, mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req auth.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.AuthService.Refresh(req.RefreshToken, constants.CAREER, clientInfo(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req auth.RefreshRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	if err := h.AuthService.Logout(req.RefreshToken, middleware.GetSessionID(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *UserHandler) RegisterCareer(w http.ResponseWriter, r *http.Request) {
 	type RegisterRequest struct {
 		FirstName   string `json:"firstName"`
@@ -315,22 +542,19 @@ func (h *UserHandler) RegisterCareer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword := utils.EncodeToSHA(req.Password)
-
 	newUser := models.User{
-		Id:               primitive.NewObjectID(),
-		CareerEmail:      req.CareerEmail,
-		Password:         hashedPassword,
-		CreateAt:         primitive.NewDateTimeFromTime(time.Now()),
-		IsDeleted:        false,
-		Role:             "Career",
-		FirstName:        req.FirstName,
-		LastName:         req.LastName,
-		CareerPhone:      req.CareerPhone,
-		CareerPicture:    "",
-		Languages:        nil,
-		Profile:          models.Profile{},
-		VerificationCode: "",
+		Id:            primitive.NewObjectID(),
+		CareerEmail:   req.CareerEmail,
+		Password:      req.Password,
+		CreateAt:      primitive.NewDateTimeFromTime(time.Now()),
+		IsDeleted:     false,
+		Role:          constants.CAREER,
+		FirstName:     req.FirstName,
+		LastName:      req.LastName,
+		CareerPhone:   req.CareerPhone,
+		CareerPicture: "",
+		Languages:     nil,
+		Profile:       models.Profile{},
 	}
 
 	err = h.UserService.CreateUser(newUser)
@@ -346,10 +570,41 @@ func (h *UserHandler) RegisterCareer(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "User registered successfully",
+		"message": "User registered successfully, please check your email to verify the account",
 	})
 }
 
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if err := h.VerificationService.VerifyEmail(constants.CAREER, r.URL.Query().Get("token")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Email verified successfully"})
+}
+
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req interfaces.ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	err := h.VerificationService.ResendVerification(constants.CAREER, req.Email, utils.ClientIP(r))
+	if errors.Is(err, service.ErrVerificationThrottled) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
@@ -361,6 +616,10 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	updatedUser, err := h.UserService.UpdateUserByID(id, user)
+	if errors.Is(err, service.ErrEmailTaken) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -399,18 +658,24 @@ func (h *UserHandler) RemoveResume(w http.ResponseWriter, r *http.Request) {
 }
 func (h *UserHandler) RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
 	var req interfaces.PasswordResetRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	middleware.SetAuditActor(r, req.Email)
 
-	code, err := h.UserService.RequestPasswordReset(req.Email)
+	err := h.UserService.RequestPasswordReset(req.Email, utils.ClientIP(r))
+	if errors.Is(err, service.ErrPasswordResetThrottled) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, "Có lỗi xảy ra, vui lòng thử lại sau", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"code": code})
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Nếu email tồn tại, mã xác nhận đã được gửi"})
 }
 
 func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
@@ -419,6 +684,7 @@ func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	middleware.SetAuditActor(r, req.Email)
 
 	if err := h.UserService.ResetPassword(req.Email, req.Code, req.NewPassword); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -457,3 +723,56 @@ func (h *UserHandler) GetAppliedJob(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(result)
 }
+
+// GetViewedJobs lists the jobs the career opened lately
+func (h *UserHandler) GetViewedJobs(w http.ResponseWriter, r *http.Request) {
+	page, err := positiveQueryInt(r, "page")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageSize, err := positiveQueryInt(r, "pageSize")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.JobService.GetViewedJobs(mux.Vars(r)["id"], page, pageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetRecommendedJobs lists the open jobs matching the profile and history of the career,
+// each with the reasons it was recommended
+func (h *UserHandler) GetRecommendedJobs(w http.ResponseWriter, r *http.Request) {
+	page, err := positiveQueryInt(r, "page")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pageSize, err := positiveQueryInt(r, "pageSize")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.JobService.RecommendJobs(mux.Vars(r)["id"], page, pageSize)
+	if errors.Is(err, jobs.ErrCareerNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -1,6 +1,7 @@
 package decorator
 
 import (
+	"hireforwork-server/api/router/types"
 	"net/http"
 )
 
@@ -21,6 +22,13 @@ type RouteMetadata struct {
 	Path         string
 	Method       HTTPMethod
 	RequiresAuth bool
+	Roles        []string
+	MemberRoles  []string
+	Scopes       []string
+	Owner        types.OwnershipRule
+	Credentials  bool
+	AuditAction  string
+	AuditTarget  string
 	Handler      http.HandlerFunc
 }
 type Controller interface {
@@ -35,6 +43,44 @@ func Route(path string, method HTTPMethod, requiresAuth bool) RouteMetadata {
 	}
 }
 
+// WithRoles restricts the route to the given roles
+func (m RouteMetadata) WithRoles(roles ...string) RouteMetadata {
+	m.Roles = roles
+	return m
+}
+
+// WithMemberRoles restricts company tokens to the given member roles
+func (m RouteMetadata) WithMemberRoles(roles ...string) RouteMetadata {
+	m.MemberRoles = roles
+	return m
+}
+
+// WithScopes lets API keys holding one of the scopes call the route
+func (m RouteMetadata) WithScopes(scopes ...string) RouteMetadata {
+	m.Scopes = scopes
+	return m
+}
+
+// OwnedBy restricts the route to the owner of the resource in {id}
+func (m RouteMetadata) OwnedBy(rule types.OwnershipRule) RouteMetadata {
+	m.Owner = rule
+	return m
+}
+
+// ManagesCredentials marks a route changing the credentials, sessions or existence of
+// the account, impersonated sessions cannot call it
+func (m RouteMetadata) ManagesCredentials() RouteMetadata {
+	m.Credentials = true
+	return m
+}
+
+// Audited records every call of the route as action on a resource of targetType
+func (m RouteMetadata) Audited(action, targetType string) RouteMetadata {
+	m.AuditAction = action
+	m.AuditTarget = targetType
+	return m
+}
+
 func Get(path string, requiresAuth bool) RouteMetadata {
 	return Route(path, GET, requiresAuth)
 }
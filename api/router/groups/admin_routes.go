@@ -0,0 +1,45 @@
+package groups
+
+import (
+	"hireforwork-server/api/router/decorator"
+	"hireforwork-server/api/router/types"
+	"hireforwork-server/constants"
+	"hireforwork-server/service/modules/audit"
+)
+
+// AdminRoutes returns the platform administration routes, all restricted to ADMIN
+func AdminRoutes() []types.RouteConfig {
+	routes := []decorator.RouteMetadata{
+		decorator.Get("/admin/audit-logs", true),
+		decorator.Get("/admin/audit-logs/export", true).Audited(audit.ActionAuditExport, audit.TargetAuditLog),
+		decorator.Get("/admin/careers", true),
+		decorator.Post("/admin/careers/{id}/suspend", true).Audited(audit.ActionAccountSuspend, audit.TargetCareer),
+		decorator.Post("/admin/careers/{id}/restore", true).Audited(audit.ActionAccountRestore, audit.TargetCareer),
+		decorator.Post("/admin/careers/{id}/impersonate", true).Audited(audit.ActionImpersonate, audit.TargetCareer),
+		decorator.Get("/admin/companies", true),
+		decorator.Post("/admin/companies/{id}/suspend", true).Audited(audit.ActionAccountSuspend, audit.TargetCompany),
+		decorator.Post("/admin/companies/{id}/restore", true).Audited(audit.ActionAccountRestore, audit.TargetCompany),
+		decorator.Post("/admin/companies/{id}/impersonate", true).Audited(audit.ActionImpersonate, audit.TargetCompany),
+		decorator.Post("/admin/jobs/{id}/close", true).Audited(audit.ActionJobClose, audit.TargetJob),
+		decorator.Delete("/admin/jobs/{id}", true).Audited(audit.ActionJobDelete, audit.TargetJob),
+		decorator.Get("/admin/reports", true),
+		decorator.Get("/admin/statistics", true),
+		decorator.Post("/admin/reports/{id}/resolve", true).Audited(audit.ActionReportResolve, audit.TargetReport),
+	}
+
+	// Convert decorator metadata to RouteConfig
+	configs := make([]types.RouteConfig, len(routes))
+	for i, route := range routes {
+		configs[i] = types.RouteConfig{
+			Path:         route.Path,
+			Handler:      "admin",
+			Methods:      []string{string(route.Method)},
+			RequiresAuth: route.RequiresAuth,
+			Roles:        []string{constants.ADMIN},
+			AuditAction:  route.AuditAction,
+			AuditTarget:  route.AuditTarget,
+		}
+	}
+
+	return configs
+}
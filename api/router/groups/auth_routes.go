@@ -0,0 +1,17 @@
+package groups
+
+import (
+	"hireforwork-server/api/router/types"
+)
+
+// AuthRoutes returns the routes shared by every account type
+func AuthRoutes() []types.RouteConfig {
+	return []types.RouteConfig{
+		{
+			Path:         "/.well-known/jwks.json",
+			Methods:      []string{"GET"},
+			RequiresAuth: false,
+			Handler:      "auth",
+		},
+	}
+}
@@ -3,23 +3,44 @@ package groups
 import (
 	"hireforwork-server/api/router/decorator"
 	"hireforwork-server/api/router/types"
+	"hireforwork-server/constants"
+	"hireforwork-server/service/modules/audit"
 )
 
 // CareerRoutes returns all career-related routes using decorator pattern
 func CareerRoutes() []types.RouteConfig {
 	routes := []decorator.RouteMetadata{
-		decorator.Post("/careers/auth/login", false),
+		decorator.Post("/careers/auth/login", false).Audited(audit.ActionLogin, audit.TargetCareer),
+		decorator.Post("/careers/auth/refresh", false),
+		decorator.Post("/careers/auth/logout", false).Audited(audit.ActionLogout, audit.TargetSession),
+		decorator.Get("/careers/auth/oidc/authorize", false),
+		decorator.Get("/careers/auth/oidc/callback", false).Audited(audit.ActionLoginOIDC, audit.TargetCareer),
+		decorator.Post("/careers/auth/oidc/callback", false).Audited(audit.ActionLoginOIDC, audit.TargetCareer),
+		decorator.Post("/careers/auth/mfa/verify", false).Audited(audit.ActionLoginMFA, audit.TargetCareer),
+		decorator.Post("/careers/mfa/enroll", false),
+		decorator.Post("/careers/mfa/confirm", false).Audited(audit.ActionMFAEnable, audit.TargetCareer),
+		decorator.Post("/careers/mfa/disable", true).WithRoles(constants.CAREER, constants.ADMIN).ManagesCredentials().Audited(audit.ActionMFADisable, audit.TargetCareer),
 		decorator.Post("/careers/register", false),
-		decorator.Post("/careers/create", false),
-		decorator.Get("/careers", true),
-		decorator.Get("/careers/{id}", true),
-		decorator.Delete("/careers/{id}", true),
-		decorator.Get("/careers/{id}/save-job", true),
-		decorator.Get("/careers/{id}/applied-job", true),
-		decorator.Post("/careers/{id}/upload-image", true),
-		decorator.Post("/careers/{id}/upload-resume", true),
-		decorator.Post("/careers/{id}/remove-resume", true),
-		decorator.Post("/careers/{id}/update", true),
+		decorator.Get("/careers/verify-email", false),
+		decorator.Post("/careers/resend-verification", false),
+		decorator.Post("/request-password-reset", false).Audited(audit.ActionPasswordResetRequest, audit.TargetCareer),
+		decorator.Post("/reset-password", false).Audited(audit.ActionPasswordReset, audit.TargetCareer),
+		decorator.Post("/careers/create", true).WithRoles(constants.ADMIN).Audited(audit.ActionCareerCreate, audit.TargetCareer),
+		decorator.Get("/careers", true).WithRoles(constants.ADMIN),
+		decorator.Get("/careers/sessions", true).WithRoles(constants.CAREER, constants.ADMIN),
+		decorator.Post("/careers/sessions/revoke-others", true).WithRoles(constants.CAREER, constants.ADMIN).ManagesCredentials().Audited(audit.ActionSessionRevoke, audit.TargetSession),
+		decorator.Delete("/careers/sessions/{id}", true).WithRoles(constants.CAREER, constants.ADMIN).ManagesCredentials().Audited(audit.ActionSessionRevoke, audit.TargetSession),
+		decorator.Get("/careers/{id}", true).WithRoles(constants.CAREER, constants.COMPANY, constants.ADMIN).OwnedBy(types.OwnerApplicant),
+		decorator.Delete("/careers/{id}", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer).ManagesCredentials().Audited(audit.ActionCareerDelete, audit.TargetCareer),
+		decorator.Get("/careers/{id}/save-job", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Get("/careers/{id}/applied-job", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Get("/careers/{id}/viewed-jobs", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Get("/careers/{id}/recommended-jobs", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Post("/careers/{id}/upload-image", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Post("/careers/{id}/upload-resume", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Post("/careers/{id}/remove-resume", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer),
+		decorator.Post("/careers/{id}/update", true).WithRoles(constants.CAREER, constants.ADMIN).OwnedBy(types.OwnerCareer).ManagesCredentials().Audited(audit.ActionCareerUpdate, audit.TargetCareer),
+		decorator.Post("/careers/{id}/unlock", true).WithRoles(constants.ADMIN).Audited(audit.ActionAccountUnlock, audit.TargetCareer),
 	}
 
 	// Convert decorator metadata to RouteConfig
@@ -30,6 +51,13 @@ func CareerRoutes() []types.RouteConfig {
 			Handler:      "career",
 			Methods:      []string{string(route.Method)},
 			RequiresAuth: route.RequiresAuth,
+			Roles:        route.Roles,
+			MemberRoles:  route.MemberRoles,
+			Scopes:       route.Scopes,
+			Owner:        route.Owner,
+			Credentials:  route.Credentials,
+			AuditAction:  route.AuditAction,
+			AuditTarget:  route.AuditTarget,
 		}
 	}
 
@@ -3,18 +3,46 @@ package groups
 import (
 	"hireforwork-server/api/router/decorator"
 	"hireforwork-server/api/router/types"
+	"hireforwork-server/constants"
+	"hireforwork-server/service/modules/audit"
 )
 
 // CompanyRoutes returns all company-related routes using decorator pattern
 func CompanyRoutes() []types.RouteConfig {
 	routes := []decorator.RouteMetadata{
 		decorator.Post("/companies", false),
-		decorator.Post("/companies/auth/login", false),
+		decorator.Post("/companies/auth/login", false).Audited(audit.ActionLogin, audit.TargetCompany),
+		decorator.Post("/companies/auth/refresh", false),
+		decorator.Post("/companies/auth/logout", false).Audited(audit.ActionLogout, audit.TargetSession),
+		decorator.Post("/companies/auth/mfa/verify", false).Audited(audit.ActionLoginMFA, audit.TargetCompany),
+		decorator.Post("/companies/mfa/enroll", false),
+		decorator.Post("/companies/mfa/confirm", false).Audited(audit.ActionMFAEnable, audit.TargetCompany),
+		decorator.Post("/companies/mfa/disable", true).WithRoles(constants.COMPANY).ManagesCredentials().Audited(audit.ActionMFADisable, audit.TargetCompany),
 		decorator.Post("/companies/forgot-password", false),
+		decorator.Post("/companies/create", false),
+		decorator.Get("/companies/verify-email", false),
+		decorator.Post("/companies/resend-verification", false),
+		decorator.Post("/request-password-reset-company", false).Audited(audit.ActionPasswordResetRequest, audit.TargetCompany),
+		decorator.Post("/reset-password-company", false).Audited(audit.ActionPasswordReset, audit.TargetCompany),
 		decorator.Get("/companies", false),
+		decorator.Get("/companies/members", true).WithRoles(constants.COMPANY),
+		decorator.Post("/companies/members/invite", true).WithRoles(constants.COMPANY).WithMemberRoles(constants.MEMBER_OWNER).ManagesCredentials().Audited(audit.ActionMemberInvite, audit.TargetMember),
+		decorator.Post("/companies/members/accept", false),
+		decorator.Delete("/companies/members/{id}", true).WithRoles(constants.COMPANY).WithMemberRoles(constants.MEMBER_OWNER).ManagesCredentials().Audited(audit.ActionMemberRemove, audit.TargetMember),
+		decorator.Get("/companies/api-keys", true).WithRoles(constants.COMPANY).WithMemberRoles(constants.MEMBER_OWNER),
+		decorator.Post("/companies/api-keys", true).WithRoles(constants.COMPANY).WithMemberRoles(constants.MEMBER_OWNER).ManagesCredentials().Audited(audit.ActionAPIKeyCreate, audit.TargetAPIKey),
+		decorator.Delete("/companies/api-keys/{id}", true).WithRoles(constants.COMPANY).WithMemberRoles(constants.MEMBER_OWNER).ManagesCredentials().Audited(audit.ActionAPIKeyRevoke, audit.TargetAPIKey),
+		decorator.Post("/companies/change-application-status", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER, constants.MEMBER_RECRUITER).Audited(audit.ActionApplicationStatus, audit.TargetApplication),
+		decorator.Get("/companies/sessions", true).WithRoles(constants.COMPANY),
+		decorator.Post("/companies/sessions/revoke-others", true).WithRoles(constants.COMPANY).ManagesCredentials().Audited(audit.ActionSessionRevoke, audit.TargetSession),
+		decorator.Delete("/companies/sessions/{id}", true).WithRoles(constants.COMPANY).ManagesCredentials().Audited(audit.ActionSessionRevoke, audit.TargetSession),
 		decorator.Get("/companies/{id}", false),
-		decorator.Put("/companies/{id}", true),
-		decorator.Delete("/companies/{id}", true),
+		decorator.Get("/companies/{id}/jobs", true).WithRoles(constants.COMPANY, constants.ADMIN).WithScopes(constants.SCOPE_JOBS_READ).OwnedBy(types.OwnerCompany),
+		decorator.Get("/companies/{id}/get-applier", true).WithRoles(constants.COMPANY, constants.ADMIN).WithScopes(constants.SCOPE_APPLICANTS_READ).OwnedBy(types.OwnerCompany),
+		decorator.Put("/companies/{id}", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER).OwnedBy(types.OwnerCompany).Audited(audit.ActionCompanyUpdate, audit.TargetCompany),
+		decorator.Delete("/companies/{id}", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER).OwnedBy(types.OwnerCompany).ManagesCredentials().Audited(audit.ActionCompanyDelete, audit.TargetCompany),
+		decorator.Post("/companies/{id}/unlock", true).WithRoles(constants.ADMIN).Audited(audit.ActionAccountUnlock, audit.TargetCompany),
+		decorator.Post("/companies/{id}/report", true).WithRoles(constants.CAREER),
 	}
 
 	// Convert decorator metadata to RouteConfig
@@ -25,6 +53,13 @@ func CompanyRoutes() []types.RouteConfig {
 			Handler:      "company",
 			Methods:      []string{string(route.Method)},
 			RequiresAuth: route.RequiresAuth,
+			Roles:        route.Roles,
+			MemberRoles:  route.MemberRoles,
+			Scopes:       route.Scopes,
+			Owner:        route.Owner,
+			Credentials:  route.Credentials,
+			AuditAction:  route.AuditAction,
+			AuditTarget:  route.AuditTarget,
 		}
 	}
 
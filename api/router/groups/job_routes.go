@@ -3,21 +3,25 @@ package groups
 import (
 	"hireforwork-server/api/router/decorator"
 	"hireforwork-server/api/router/types"
+	"hireforwork-server/constants"
+	"hireforwork-server/service/modules/audit"
 )
 
 // JobRoutes returns all job-related routes using decorator pattern
 func JobRoutes() []types.RouteConfig {
 	routes := []decorator.RouteMetadata{
 		decorator.Get("/jobs", false),
-		decorator.Post("/jobs", true),
-		decorator.Put("/jobs", true),
+		decorator.Post("/jobs", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER, constants.MEMBER_RECRUITER).WithScopes(constants.SCOPE_JOBS_WRITE).Audited(audit.ActionJobCreate, audit.TargetJob),
+		decorator.Put("/jobs", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER, constants.MEMBER_RECRUITER).WithScopes(constants.SCOPE_JOBS_WRITE).Audited(audit.ActionJobUpdate, audit.TargetJob),
 		decorator.Get("/jobs/{id}", false),
-		decorator.Post("/jobs/{id}/apply", true),
-		decorator.Post("/jobs/{id}/save", true),
-		decorator.Post("/jobs/{id}/unsave", true),
-		decorator.Post("/jobs/{id}/apply", true),
-		decorator.Put("/jobs/{id}", true),
-		decorator.Delete("/jobs/{id}", true),
+		decorator.Post("/jobs/{id}/apply", true).WithRoles(constants.CAREER),
+		decorator.Post("/jobs/{id}/save", true).WithRoles(constants.CAREER),
+		decorator.Post("/jobs/{id}/unsave", true).WithRoles(constants.CAREER),
+		decorator.Post("/jobs/{id}/report", true).WithRoles(constants.CAREER),
+		decorator.Put("/jobs/{id}", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER, constants.MEMBER_RECRUITER).WithScopes(constants.SCOPE_JOBS_WRITE).OwnedBy(types.OwnerJob).Audited(audit.ActionJobUpdate, audit.TargetJob),
+		decorator.Post("/jobs/{id}/status", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER, constants.MEMBER_RECRUITER).WithScopes(constants.SCOPE_JOBS_WRITE).OwnedBy(types.OwnerJob).Audited(audit.ActionJobStatus, audit.TargetJob),
+		decorator.Get("/jobs/{id}/extend", false).Audited(audit.ActionJobExtend, audit.TargetJob),
+		decorator.Delete("/jobs/{id}", true).WithRoles(constants.COMPANY, constants.ADMIN).WithMemberRoles(constants.MEMBER_OWNER, constants.MEMBER_RECRUITER).WithScopes(constants.SCOPE_JOBS_WRITE).OwnedBy(types.OwnerJob).Audited(audit.ActionJobDelete, audit.TargetJob),
 	}
 
 	// Convert decorator metadata to RouteConfig
@@ -28,6 +32,13 @@ func JobRoutes() []types.RouteConfig {
 			Handler:      "job",
 			Methods:      []string{string(route.Method)},
 			RequiresAuth: route.RequiresAuth,
+			Roles:        route.Roles,
+			MemberRoles:  route.MemberRoles,
+			Scopes:       route.Scopes,
+			Owner:        route.Owner,
+			Credentials:  route.Credentials,
+			AuditAction:  route.AuditAction,
+			AuditTarget:  route.AuditTarget,
 		}
 	}
 
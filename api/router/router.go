@@ -8,6 +8,7 @@ import (
 	"hireforwork-server/db"
 	"hireforwork-server/middleware"
 	service "hireforwork-server/service"
+	"hireforwork-server/service/modules/audit"
 	auth "hireforwork-server/service/modules/auth"
 	"net/http"
 
@@ -43,9 +44,13 @@ func (b *RouterBuilder) BuildRoutes() *mux.Router {
 	routes = append(routes, groups.CareerRoutes()...)
 	routes = append(routes, groups.JobRoutes()...)
 	routes = append(routes, groups.CompanyRoutes()...)
+	routes = append(routes, groups.AuthRoutes()...)
+	routes = append(routes, groups.AdminRoutes()...)
 
 	// Create auth service
 	authService := auth.NewAuthService(b.db)
+	ownership := middleware.NewOwnershipChecker(b.db)
+	auditLogger := audit.NewLogger(b.db)
 
 	// Apply global middleware and decorators
 	b.router.Use(middleware.GlobalMiddleware(authService))
@@ -64,9 +69,20 @@ func (b *RouterBuilder) BuildRoutes() *mux.Router {
 		if handler != nil {
 			var finalHandler http.Handler = handler
 
-			// Apply JWT middleware only if route requires auth
+			// Apply JWT and role middleware only if route requires auth
 			if route.RequiresAuth {
-				finalHandler = middleware.JWTMiddleware(authService)(handler)
+				finalHandler = middleware.RoleMiddleware(route, ownership)(finalHandler)
+				finalHandler = middleware.JWTMiddleware(authService)(finalHandler)
+			}
+
+			// Audit outermost so rejected attempts are recorded as well
+			if route.AuditAction != "" {
+				finalHandler = middleware.AuditMiddleware(route, auditLogger)(finalHandler)
+			}
+
+			// API keys authenticate before the audit so calls made with them are attributed
+			if len(route.Scopes) > 0 {
+				finalHandler = middleware.APIKeyMiddleware(authService)(finalHandler)
 			}
 
 			// Create route with methods
@@ -1,11 +1,42 @@
 package types
 
+// OwnershipRule restricts a route to the owner of the resource in {id}.
+// A rule only applies to the role owning that kind of resource, ADMIN bypasses it.
+type OwnershipRule string
+
+const (
+	OwnerNone OwnershipRule = ""
+	// {id} must equal the career in the token
+	OwnerCareer OwnershipRule = "career"
+	// {id} must equal the company in the token
+	OwnerCompany OwnershipRule = "company"
+	// the job {id} must belong to the company in the token
+	OwnerJob OwnershipRule = "job"
+	// {id} must equal the career in the token, or have applied to a job of the
+	// company in the token
+	OwnerApplicant OwnershipRule = "applicant"
+)
+
 // RouteConfig defines the structure for route configuration
 type RouteConfig struct {
 	Path         string
 	Handler      string
 	Methods      []string
 	RequiresAuth bool
+	// Roles allowed to call the route, empty means any authenticated user
+	Roles []string
+	// MemberRoles further restricts COMPANY tokens by the member's role in the company
+	MemberRoles []string
+	// Scopes an API key needs to call the route, routes without scopes only accept a JWT
+	Scopes []string
+	Owner  OwnershipRule
+	// Credentials marks routes changing the credentials, sessions or existence of the
+	// account, support sessions opened by an admin cannot call them as the change would
+	// outlive them
+	Credentials bool
+	// AuditAction records every call of the route in the audit log, empty means not audited
+	AuditAction string
+	AuditTarget string
 }
 
 // RouteGroup defines a group of routes with a common prefix
@@ -2,8 +2,12 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +18,27 @@ type Config struct {
 	MongoUrl           string
 	FirebaseBucket     string
 	FirebaseCredential string
+	PasswordHasher     string
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	AppBaseURL         string
+	VerificationTTL    time.Duration
+	PasswordResetTTL   time.Duration
+	RequireAdminMFA    bool
+	JwtKeysDir         string
+	JwtActiveKid       string
+	LegacyTokensUntil  time.Time
+	OIDCIssuerURL      string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCScopes         []string
+	JobSchedulerTick   time.Duration
+	JobExpiryReminder  time.Duration
+	JobExtendPeriod    time.Duration
+	SalaryRates        map[string]float64
+	JobViewWindow      time.Duration
+	TrustedProxies     []*net.IPNet
 }
 
 var instance *Config
@@ -30,6 +55,48 @@ func GetInstance() *Config {
 		mongoUrl := os.Getenv("DATABASE_CONNECTION")
 		firebaseBucket := os.Getenv("FIREBASE_BUCKET")
 		firebaseCredential := os.Getenv("FIREBASE_CREDENTIALS")
+		// argon2id (default) or bcrypt
+		passwordHasher := os.Getenv("PASSWORD_HASHER")
+		accessTokenTTL := getDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+		refreshTokenTTL := getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+		// public URL of the API, used to build links sent by email
+		appBaseURL := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+		verificationTTL := getDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+		passwordResetTTL := getDuration("PASSWORD_RESET_TTL", 15*time.Minute)
+		// org policy: ADMIN accounts cannot sign in without TOTP
+		requireAdminMFA := os.Getenv("MFA_REQUIRED_FOR_ADMIN") == "true"
+		// PEM keys signing access tokens (RS256/EdDSA), HS256 with SECRET_KEY when unset
+		jwtKeysDir := os.Getenv("JWT_KEYS_DIR")
+		jwtActiveKid := os.Getenv("JWT_ACTIVE_KID")
+		// tokens signed with SECRET_KEY before kid headers existed are accepted until this
+		// RFC 3339 time, one access token TTL after the switch is enough. Never when unset.
+		legacyTokensUntil := getTime("JWT_ACCEPT_LEGACY_TOKENS_UNTIL")
+		// OpenID Connect provider careers can sign in with, disabled without an issuer
+		oidcIssuerURL := strings.TrimRight(os.Getenv("OIDC_ISSUER_URL"), "/")
+		oidcClientID := os.Getenv("OIDC_CLIENT_ID")
+		oidcClientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+		oidcRedirectURL := os.Getenv("OIDC_REDIRECT_URL")
+		if oidcRedirectURL == "" {
+			oidcRedirectURL = appBaseURL + "/careers/auth/oidc/callback"
+		}
+		oidcScopes := strings.Fields(os.Getenv("OIDC_SCOPES"))
+		if len(oidcScopes) == 0 {
+			oidcScopes = []string{"openid", "email", "profile"}
+		}
+
+		// how often scheduled jobs are published and overdue ones expired
+		jobSchedulerTick := getDuration("JOB_SCHEDULER_INTERVAL", time.Minute)
+		// companies are emailed this long before a job expires
+		jobExpiryReminder := getDuration("JOB_EXPIRY_REMINDER", 72*time.Hour)
+		// how much a one-click extend adds to the expiry date
+		jobExtendPeriod := getDuration("JOB_EXTEND_PERIOD", 30*24*time.Hour)
+		// VND worth one unit of each accepted salary currency, like "USD=25000,EUR=27000"
+		salaryRates := getRates("SALARY_RATES", map[string]float64{"VND": 1, "USD": 25000})
+		// a viewer opening the same job again within this window is counted once
+		jobViewWindow := getDuration("JOB_VIEW_WINDOW", 24*time.Hour)
+		// proxies in front of the API, like "10.0.0.0/8,127.0.0.1", whose X-Forwarded-For
+		// entries are believed. The header is ignored when unset.
+		trustedProxies := getNetworks("TRUSTED_PROXIES")
 
 		instance = &Config{
 			DatabaseName:       dbName,
@@ -37,7 +104,102 @@ func GetInstance() *Config {
 			SecretKey:          secretKey,
 			FirebaseBucket:     firebaseBucket,
 			FirebaseCredential: firebaseCredential,
+			PasswordHasher:     passwordHasher,
+			AccessTokenTTL:     accessTokenTTL,
+			RefreshTokenTTL:    refreshTokenTTL,
+			AppBaseURL:         appBaseURL,
+			VerificationTTL:    verificationTTL,
+			PasswordResetTTL:   passwordResetTTL,
+			RequireAdminMFA:    requireAdminMFA,
+			JwtKeysDir:         jwtKeysDir,
+			JwtActiveKid:       jwtActiveKid,
+			LegacyTokensUntil:  legacyTokensUntil,
+			OIDCIssuerURL:      oidcIssuerURL,
+			OIDCClientID:       oidcClientID,
+			OIDCClientSecret:   oidcClientSecret,
+			OIDCRedirectURL:    oidcRedirectURL,
+			OIDCScopes:         oidcScopes,
+			JobSchedulerTick:   jobSchedulerTick,
+			JobExpiryReminder:  jobExpiryReminder,
+			JobExtendPeriod:    jobExtendPeriod,
+			SalaryRates:        salaryRates,
+			JobViewWindow:      jobViewWindow,
+			TrustedProxies:     trustedProxies,
 		}
 	})
 	return instance
 }
+
+// getDuration reads a duration like "15m" or "720h", falling back when unset or invalid
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// getTime reads an RFC 3339 time, the zero time when unset or invalid
+func getTime(key string) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return time.Time{}
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("Invalid %s %q, ignoring it", key, value)
+		return time.Time{}
+	}
+	return parsed
+}
+
+// getRates reads "CODE=rate" pairs separated by commas, VND is always worth 1. Falls back
+// when unset or when any pair is invalid.
+func getRates(key string, fallback map[string]float64) map[string]float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	rates := map[string]float64{"VND": 1}
+	for _, pair := range strings.Split(value, ",") {
+		code, rateValue, found := strings.Cut(pair, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateValue), 64)
+		if !found || err != nil || rate <= 0 {
+			log.Printf("Invalid %s %q, using %v", key, value, fallback)
+			return fallback
+		}
+		rates[strings.ToUpper(strings.TrimSpace(code))] = rate
+	}
+	return rates
+}
+
+// getNetworks reads IP addresses or CIDR ranges separated by commas, skipping the invalid
+// ones
+func getNetworks(key string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			log.Printf("Invalid %s entry %q, skipping it", key, value)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -11,6 +11,95 @@ const (
 	CAREER  = "CAREER"
 	COMPANY = "COMPANY"
 )
+
+// Roles of a member inside a company account
+const (
+	MEMBER_OWNER     = "OWNER"
+	MEMBER_RECRUITER = "RECRUITER"
+	MEMBER_VIEWER    = "VIEWER"
+)
+
+// Status of a company member
+const (
+	MEMBER_INVITED = "INVITED"
+	MEMBER_ACTIVE  = "ACTIVE"
+	MEMBER_REMOVED = "REMOVED"
+)
+
+// Scopes a company can grant to an API key
+const (
+	SCOPE_JOBS_READ       = "jobs:read"
+	SCOPE_JOBS_WRITE      = "jobs:write"
+	SCOPE_APPLICANTS_READ = "applicants:read"
+)
+
+// Moderation status of a career or company account, used to filter admin listings
+const (
+	ACCOUNT_ACTIVE    = "ACTIVE"
+	ACCOUNT_SUSPENDED = "SUSPENDED"
+	ACCOUNT_DELETED   = "DELETED"
+	ACCOUNT_ANY       = "ANY"
+)
+
+// Content users can report to the moderators
+const (
+	REPORT_TARGET_JOB     = "JOB"
+	REPORT_TARGET_COMPANY = "COMPANY"
+)
+
+// Status of a content report
+const (
+	REPORT_OPEN      = "OPEN"
+	REPORT_RESOLVED  = "RESOLVED"
+	REPORT_DISMISSED = "DISMISSED"
+)
+
+// Lifecycle status of a job post, only PUBLISHED jobs are listed publicly
+const (
+	JOB_DRAFT     = "DRAFT"
+	JOB_SCHEDULED = "SCHEDULED"
+	JOB_PUBLISHED = "PUBLISHED"
+	JOB_PAUSED    = "PAUSED"
+	JOB_CLOSED    = "CLOSED"
+	JOB_EXPIRED   = "EXPIRED"
+	JOB_ARCHIVED  = "ARCHIVED"
+)
+
+// Currencies a salary can be posted in, others are accepted when config.SalaryRates lists them
+const (
+	SALARY_VND = "VND"
+	SALARY_USD = "USD"
+)
+
+// Period a salary amount is paid for
+const (
+	SALARY_HOURLY  = "HOURLY"
+	SALARY_MONTHLY = "MONTHLY"
+	SALARY_YEARLY  = "YEARLY"
+)
+
+// Whether a salary is before or after tax and insurance
+const (
+	SALARY_GROSS = "GROSS"
+	SALARY_NET   = "NET"
+)
+
+// Where the work of a job is done, remote jobs may be limited to countries or timezones
+const (
+	WORK_ONSITE = "ONSITE"
+	WORK_HYBRID = "HYBRID"
+	WORK_REMOTE = "REMOTE"
+)
+
+// Employment types a job can offer
+const (
+	EMPLOYMENT_FULL_TIME  = "FULL_TIME"
+	EMPLOYMENT_PART_TIME  = "PART_TIME"
+	EMPLOYMENT_CONTRACT   = "CONTRACT"
+	EMPLOYMENT_INTERNSHIP = "INTERNSHIP"
+	EMPLOYMENT_FREELANCE  = "FREELANCE"
+)
+
 const (
 	emailTemplate = `
 	<!DOCTYPE html>
@@ -0,0 +1,15 @@
+package interfaces
+
+type IAuditFilter struct {
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	ActorID    string `json:"actorId"`
+	ActorRole  string `json:"actorRole"`
+	Action     string `json:"action"`
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	Outcome    string `json:"outcome"`
+	IP         string `json:"ip"`
+	CreateFrom string `json:"createFrom"`
+	CreateTo   string `json:"createTo"`
+}
@@ -5,4 +5,7 @@ type ICompanyFilter struct {
 	CompanyEmail string `bson:"companyEmail" json:"companyEmail"`
 	StartDate    *string
 	EndDate      *string
+	// only honoured for admins
+	IsVerified *bool
+	Status     string
 }
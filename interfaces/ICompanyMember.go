@@ -0,0 +1,13 @@
+package interfaces
+
+type IMemberInvite struct {
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	MemberRole string `json:"memberRole"`
+}
+
+type IMemberAccept struct {
+	Token    string `json:"token"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
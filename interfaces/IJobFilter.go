@@ -9,11 +9,20 @@ type IJobFilter struct {
 	EndDateTo       string   `json:"endDateTo"`
 	SalaryFrom      int64    `json:"salaryFrom"`
 	SalaryTo        int64    `json:"salaryTo"`
+	SalaryCurrency  string   `json:"salaryCurrency"`
+	SalaryPeriod    string   `json:"salaryPeriod"`
 	WorkingLocation []string `json:"workingLocation"`
 	JobRequirement  []string `json:"jobRequirement"`
 	JobCategory     []string `json:"jobCategory"`
+	JobTech         []string `json:"jobTech"`
+	WorkArrangement []string `json:"workArrangement"`
+	EmploymentType  []string `json:"employmentType"`
+	RemoteCountry   string   `json:"remoteCountry"`
 	JobLevel        string   `json:"jobLevel"`
 	Query           string   `json:"query"`
 	IsHot           bool     `json:"isHot"`
-	IsExpire        bool     `json:"isExpire"`
+	Sort            string   `json:"sort"`
+	Cursor          string   `json:"cursor"`
+	Near            string   `json:"near"`
+	RadiusKm        float64  `json:"radiusKm"`
 }
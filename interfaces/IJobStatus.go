@@ -0,0 +1,11 @@
+package interfaces
+
+import "time"
+
+// IJobStatusRequest moves a job to another lifecycle status. PublishAt is required to
+// schedule a job, ExpireDate replaces the expiry when publishing or republishing.
+type IJobStatusRequest struct {
+	Status     string    `json:"status"`
+	PublishAt  time.Time `json:"publishAt"`
+	ExpireDate time.Time `json:"expireDate"`
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+// IModerationRequest carries the reason an admin gives for suspending an account,
+// closing a job or impersonating a user
+type IModerationRequest struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+type IReportRequest struct {
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+}
+
+type IReportFilter struct {
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	Status     string `json:"status"`
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+}
+
+type IReportResolve struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
@@ -0,0 +1,5 @@
+package interfaces
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
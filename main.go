@@ -6,6 +6,7 @@ import (
 	"hireforwork-server/db"
 	"hireforwork-server/service"
 	factory "hireforwork-server/service/modules/factory"
+	"hireforwork-server/service/modules/jobs"
 	"log"
 	"net/http"
 	"os"
@@ -59,6 +60,10 @@ func main() {
 	// Register all services at once using factory
 	serviceFactory.RegisterAllServices(container)
 
+	// Publish scheduled jobs, expire overdue ones and send expiry reminders in the background
+	jobScheduler := jobs.NewJobScheduler(container.Get("job").(*jobs.JobService))
+	jobScheduler.Start()
+
 	// Create app services
 	appServices := service.NewAppServices(container)
 
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"hireforwork-server/api/router/types"
+	"hireforwork-server/constants"
+	"hireforwork-server/db"
+	auth "hireforwork-server/service/modules/auth"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrorResponse is the body of every 401/403 returned by the middlewares
+type ErrorResponse struct {
+	StatusCode int    `json:"statusCode"`
+	Message    string `json:"message"`
+}
+
+func WriteError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		StatusCode: statusCode,
+		Message:    message,
+	})
+}
+
+// OwnershipChecker resolves who owns the resource referenced by a route's {id}
+type OwnershipChecker struct {
+	jobCollection   *mongo.Collection
+	applyCollection *mongo.Collection
+}
+
+func NewOwnershipChecker(dbInstance *db.DB) *OwnershipChecker {
+	return &OwnershipChecker{
+		jobCollection:   dbInstance.GetCollection("Job"),
+		applyCollection: dbInstance.GetCollection("CareerApplyJob"),
+	}
+}
+
+// IsOwner reports whether the token may touch the resource. Rules only bind the
+// role that owns that kind of resource, every other allowed role passes.
+func (o *OwnershipChecker) IsOwner(rule types.OwnershipRule, r *http.Request, claims *auth.Claims) bool {
+	role := auth.NormalizeRole(claims.Role)
+	if role == constants.ADMIN {
+		return true
+	}
+
+	resourceID := mux.Vars(r)["id"]
+
+	switch rule {
+	case types.OwnerCareer:
+		return role != constants.CAREER || resourceID == claims.Subject
+	case types.OwnerCompany:
+		return role != constants.COMPANY || resourceID == claims.GetCompanyID()
+	case types.OwnerJob:
+		if role != constants.COMPANY {
+			return true
+		}
+		return o.jobCompanyID(r.Context(), resourceID) == claims.GetCompanyID()
+	case types.OwnerApplicant:
+		switch role {
+		case constants.CAREER:
+			return resourceID == claims.Subject
+		case constants.COMPANY:
+			return o.hasApplied(r.Context(), resourceID, claims.GetCompanyID())
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+func (o *OwnershipChecker) jobCompanyID(ctx context.Context, jobID string) string {
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return ""
+	}
+
+	var job struct {
+		CompanyID primitive.ObjectID `bson:"companyID"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"companyID": 1})
+	if err := o.jobCollection.FindOne(ctx, bson.M{"_id": _id}, opts).Decode(&job); err != nil {
+		return ""
+	}
+	return job.CompanyID.Hex()
+}
+
+// hasApplied reports whether the career applied to any job of the company
+func (o *OwnershipChecker) hasApplied(ctx context.Context, careerID string, companyID string) bool {
+	careerObjID, err := primitive.ObjectIDFromHex(careerID)
+	if err != nil {
+		return false
+	}
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return false
+	}
+	return o.applyCollection.FindOne(ctx, bson.M{"careerID": careerObjID, "companyID": companyObjID}).Err() == nil
+}
+
+// RoleMiddleware enforces the roles and ownership rule declared on a RouteConfig
+func RoleMiddleware(route types.RouteConfig, ownership *OwnershipChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaims(r)
+			if claims == nil {
+				WriteError(w, http.StatusUnauthorized, "Authentication required")
+				return
+			}
+
+			if !hasRole(route.Roles, auth.NormalizeRole(claims.Role)) {
+				WriteError(w, http.StatusForbidden, "Bạn không có quyền thực hiện thao tác này")
+				return
+			}
+
+			if claims.APIKeyID != "" && !claims.HasScope(route.Scopes) {
+				WriteError(w, http.StatusForbidden, "API key không có quyền truy cập tài nguyên này")
+				return
+			}
+
+			if route.Credentials && claims.ImpersonatedBy != "" {
+				WriteError(w, http.StatusForbidden, "Phiên hỗ trợ không được thay đổi thông tin đăng nhập của tài khoản")
+				return
+			}
+
+			if !hasMemberRole(route.MemberRoles, claims) {
+				WriteError(w, http.StatusForbidden, "Vai trò của bạn trong công ty không được phép thực hiện thao tác này")
+				return
+			}
+
+			if route.Owner != types.OwnerNone && !ownership.IsOwner(route.Owner, r, claims) {
+				WriteError(w, http.StatusForbidden, "Bạn không có quyền truy cập tài nguyên này")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasRole(allowed []string, role string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, r := range allowed {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMemberRole only restricts company tokens, tokens issued before members existed
+// belong to the company account itself and act as its owner
+func hasMemberRole(allowed []string, claims *auth.Claims) bool {
+	if len(allowed) == 0 || auth.NormalizeRole(claims.Role) != constants.COMPANY {
+		return true
+	}
+	memberRole := claims.MemberRole
+	if memberRole == "" {
+		memberRole = constants.MEMBER_OWNER
+	}
+	return hasRole(allowed, memberRole)
+}
+
+// GetRole returns the normalized role of the current token
+func GetRole(r *http.Request) string {
+	if claims := GetClaims(r); claims != nil {
+		return auth.NormalizeRole(claims.Role)
+	}
+	return ""
+}
+
+// GetCompanyID returns the company acting in the current token, empty for other roles.
+// For company members this is their company, not the member ID in the subject.
+func GetCompanyID(r *http.Request) string {
+	claims := GetClaims(r)
+	if claims == nil || auth.NormalizeRole(claims.Role) != constants.COMPANY {
+		return ""
+	}
+	return claims.GetCompanyID()
+}
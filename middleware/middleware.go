@@ -13,6 +13,7 @@ import (
 type contextKey string
 
 const UserIDKey contextKey = "userID"
+const ClaimsKey contextKey = "claims"
 
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -50,11 +51,33 @@ func GlobalMiddleware(authService *auth.AuthService) func(http.Handler) http.Han
 				tokenString := strings.TrimSpace(strings.Replace(authHeader, "Bearer ", "", 1))
 				claims, err := authService.ValidateToken(tokenString)
 
-				if err == nil {
+				// Tokens of logged out or revoked sessions are treated as anonymous
+				if err == nil && !authService.IsSessionRevoked(claims) {
 					// Token is valid, add user info to context
 					ctx := context.WithValue(r.Context(), UserIDKey, claims.Subject)
+					ctx = context.WithValue(ctx, ClaimsKey, claims)
 					r = r.WithContext(ctx)
 					log.Printf("User authenticated: %s", claims.Subject)
+					authService.TouchSession(claims.SessionID)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyMiddleware lets ATS integrations authenticate with a company API key instead of
+// a JWT. It is only applied to routes declaring scopes, so a key never acts as its
+// company anywhere else.
+func APIKeyMiddleware(authService *auth.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := r.Header.Get("X-Api-Key")
+			if apiKey != "" && r.Header.Get("Authorization") == "" {
+				if claims, err := authService.AuthenticateAPIKey(apiKey); err == nil {
+					ctx := context.WithValue(r.Context(), UserIDKey, claims.Subject)
+					ctx = context.WithValue(ctx, ClaimsKey, claims)
+					r = r.WithContext(ctx)
 				}
 			}
 			next.ServeHTTP(w, r)
@@ -68,7 +91,7 @@ func JWTMiddleware(authService *auth.AuthService) func(http.Handler) http.Handle
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userID := GetUserID(r)
 			if userID == "" {
-				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				WriteError(w, http.StatusUnauthorized, "Authentication required")
 				return
 			}
 
@@ -84,3 +107,19 @@ func GetUserID(r *http.Request) string {
 	}
 	return ""
 }
+
+// GetClaims helper function to get the token claims from context
+func GetClaims(r *http.Request) *auth.Claims {
+	if claims, ok := r.Context().Value(ClaimsKey).(*auth.Claims); ok {
+		return claims
+	}
+	return nil
+}
+
+// GetSessionID returns the session of the current access token
+func GetSessionID(r *http.Request) string {
+	if claims := GetClaims(r); claims != nil {
+		return claims.SessionID
+	}
+	return ""
+}
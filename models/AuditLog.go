@@ -0,0 +1,20 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AuditLog records who did what to which resource, entries are never updated or deleted
+type AuditLog struct {
+	Id         primitive.ObjectID `bson:"_id" json:"_id"`
+	ActorID    string             `bson:"actorID" json:"actorId"`
+	ActorName  string             `bson:"actorName" json:"actorName"`
+	ActorRole  string             `bson:"actorRole" json:"actorRole"`
+	CompanyID  string             `bson:"companyID,omitempty" json:"companyId,omitempty"`
+	Action     string             `bson:"action" json:"action"`
+	TargetType string             `bson:"targetType" json:"targetType"`
+	TargetID   string             `bson:"targetID" json:"targetId"`
+	IP         string             `bson:"ip" json:"ip"`
+	UserAgent  string             `bson:"userAgent" json:"userAgent"`
+	Outcome    string             `bson:"outcome" json:"outcome"`
+	Detail     string             `bson:"detail,omitempty" json:"detail,omitempty"`
+	CreateAt   primitive.DateTime `bson:"createAt" json:"createAt"`
+}
@@ -3,11 +3,13 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type ViewedJob struct {
-    JobID primitive.ObjectID `bson:"jobID" json:"jobID"`
+	JobID    primitive.ObjectID `bson:"jobID" json:"jobID"`
+	ViewedAt primitive.DateTime `bson:"viewedAt" json:"viewedAt"`
 }
 
+// CareerViewedJob keeps the jobs a career opened lately, the most recent first
 type CareerViewedJob struct {
-    ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
-    CareerID  primitive.ObjectID `bson:"careerID" json:"careerID"`
-    ViewedJob []ViewedJob        `bson:"viewedJob" json:"viewedJob"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	CareerID  primitive.ObjectID `bson:"careerID" json:"careerID"`
+	ViewedJob []ViewedJob        `bson:"viewedJob" json:"viewedJob"`
 }
@@ -0,0 +1,19 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// CompanyAPIKey lets an ATS call the API on behalf of a company, only the hash of
+// the key is stored and the prefix is kept to tell keys apart
+type CompanyAPIKey struct {
+	Id         primitive.ObjectID `bson:"_id" json:"_id"`
+	CompanyID  primitive.ObjectID `bson:"companyID" json:"companyID"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	KeyHash    string             `bson:"keyHash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedBy  primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreateAt   primitive.DateTime `bson:"createAt" json:"createAt"`
+	LastUsedAt primitive.DateTime `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	IsRevoked  bool               `bson:"isRevoked" json:"isRevoked"`
+	RevokedAt  primitive.DateTime `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// CompanyMember is a person signing in on behalf of a company with their own credentials
+type CompanyMember struct {
+	Id         primitive.ObjectID `bson:"_id" json:"_id"`
+	CompanyID  primitive.ObjectID `bson:"companyID" json:"companyID"`
+	Email      string             `bson:"email" json:"email"`
+	Name       string             `bson:"name" json:"name"`
+	Password   string             `bson:"password,omitempty" json:"-"`
+	MemberRole string             `bson:"memberRole" json:"memberRole"`
+	Status     string             `bson:"status" json:"status"`
+	InvitedBy  primitive.ObjectID `bson:"invitedBy" json:"invitedBy"`
+	CreateAt   primitive.DateTime `bson:"createAt" json:"createAt"`
+	JoinedAt   primitive.DateTime `bson:"joinedAt,omitempty" json:"joinedAt,omitempty"`
+	MFA        MFASettings        `bson:"mfa,omitempty" json:"-"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ExternalIdentity links an account to a subject of an OpenID Connect provider
+type ExternalIdentity struct {
+	Issuer   string             `bson:"issuer" json:"issuer"`
+	Subject  string             `bson:"subject" json:"subject"`
+	LinkedAt primitive.DateTime `bson:"linkedAt" json:"linkedAt"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// GeoPoint is a GeoJSON point, Coordinates holds the longitude then the latitude
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
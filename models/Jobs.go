@@ -11,15 +11,18 @@ type UserInfo struct {
 }
 
 type Jobs struct {
-	Id               primitive.ObjectID `bson:"_id" json:"_id" ,omitempty`
+	Id               primitive.ObjectID `bson:"_id" json:"_id"`
 	JobTitle         string             `bson:"jobTitle" json:"jobTitle" validate:"required"`
 	JobSalaryMin     int64              `bson:"jobSalaryMin" json:"jobSalaryMin"`
 	JobSalaryMax     int64              `bson:"jobSalaryMax" json:"jobSalaryMax"`
+	Salary           JobSalary          `bson:"salary" json:"salary"`
 	JobRequirement   []string           `bson:"jobRequirement" json:"jobRequirement"`
 	JobCategory      []string           `bson:"jobCategory" json:"jobCategory"`
 	WorkingLocation  []string           `bson:"workingLocation" json:"workingLocation"`
 	IsHot            bool               `bson:"isHot" json:"isHot"`
 	IsClosed         bool               `bson:"isClosed" json:"isClosed"`
+	ClosedByAdmin    bool               `bson:"closedByAdmin,omitempty" json:"closedByAdmin,omitempty"`
+	CloseReason      string             `bson:"closeReason,omitempty" json:"closeReason,omitempty"`
 	IsDeleted        bool               `bson:"isDeleted" json:"isDeleted"`
 	CreateAt         primitive.DateTime `bson:"createAt" json:"createAt"`
 	ExpireDate       primitive.DateTime `bson:"expireDate" json:"expireDate"`
@@ -28,6 +31,47 @@ type Jobs struct {
 	Quantity         int64              `bson:"quantity" json:"quantity"`
 	JobDescription   string             `bson:"jobDescription" json:"jobDescription"`
 	JobLevel         string             `bson:"jobLevel" json:"jobLevel"`
-	WorkType         []string           `bson:"workingType" json:"workingType"`
+	WorkArrangement  WorkArrangement    `bson:"workArrangement" json:"workArrangement"`
+	EmploymentType   []string           `bson:"employmentType" json:"employmentType"`
 	RecruitmentCount int64              `bson:"recruitmentCount" json:"recruitmentCount"`
+	ApplicationCount int64              `bson:"applicationCount" json:"applicationCount"`
+	ViewCount        int64              `bson:"viewCount" json:"viewCount"`
+	// Lifecycle, IsClosed is kept in sync for older clients
+	Status           string             `bson:"status" json:"status"`
+	PublishAt        primitive.DateTime `bson:"publishAt,omitempty" json:"publishAt,omitempty"`
+	PublishedAt      primitive.DateTime `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`
+	ExpiryNotifiedAt primitive.DateTime `bson:"expiryNotifiedAt,omitempty" json:"expiryNotifiedAt,omitempty"`
+	Search           JobSearch          `bson:"search" json:"-"`
+	// Points of the working locations, or of the company when none is known
+	GeoPoints []GeoPoint `bson:"geoPoints" json:"geoPoints,omitempty"`
+}
+
+// WorkArrangement tells where the work is done. Countries (ISO 3166 codes) and
+// Timezones (IANA names) limit who can work remotely, empty means anywhere.
+type WorkArrangement struct {
+	Type      string   `bson:"type" json:"type"`
+	Countries []string `bson:"countries,omitempty" json:"countries,omitempty"`
+	Timezones []string `bson:"timezones,omitempty" json:"timezones,omitempty"`
+}
+
+// JobSalary describes JobSalaryMin and JobSalaryMax, a bound left at 0 is open.
+// MonthlyMin and MonthlyMax are the same range in VND per month, computed by the server
+// for filtering and sorting, and left at 0 when the salary is hidden.
+type JobSalary struct {
+	Currency   string `bson:"currency" json:"currency"`
+	Period     string `bson:"period" json:"period"`
+	Basis      string `bson:"basis" json:"basis"`
+	Negotiable bool   `bson:"negotiable" json:"negotiable"`
+	Hidden     bool   `bson:"hidden" json:"hidden"`
+	MonthlyMin int64  `bson:"monthlyMin" json:"monthlyMin"`
+	MonthlyMax int64  `bson:"monthlyMax" json:"monthlyMax"`
+}
+
+// JobSearch holds the searchable text of a job folded by utils.FoldText, the text
+// index covers these fields so searches ignore case and Vietnamese diacritics
+type JobSearch struct {
+	Title   string `bson:"title"`
+	Tech    string `bson:"tech"`
+	Company string `bson:"company"`
+	Body    string `bson:"body"`
 }
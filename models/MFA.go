@@ -0,0 +1,16 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MFASettings holds the TOTP enrollment of an account. Secrets are encrypted and
+// recovery codes hashed, none of it is ever serialized to clients.
+type MFASettings struct {
+	Enabled       bool               `bson:"enabled"`
+	Secret        string             `bson:"secret,omitempty"`
+	PendingSecret string             `bson:"pendingSecret,omitempty"`
+	RecoveryCodes []string           `bson:"recoveryCodes,omitempty"`
+	LastUsedStep  int64              `bson:"lastUsedStep,omitempty"`
+	EnabledAt     primitive.DateTime `bson:"enabledAt,omitempty"`
+	// wrong codes given for the pending secret, it is dropped after too many
+	PendingFailures int `bson:"pendingFailures,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// OIDCState keeps the PKCE verifier and nonce of an authorization request until
+// the provider redirects back with the same state
+type OIDCState struct {
+	Id       string             `bson:"_id" json:"-"`
+	Verifier string             `bson:"verifier" json:"-"`
+	Nonce    string             `bson:"nonce" json:"-"`
+	ExpireAt primitive.DateTime `bson:"expireAt" json:"-"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// PasswordReset is a single-use reset code, only its hash is stored
+type PasswordReset struct {
+	Id          primitive.ObjectID `bson:"_id" json:"_id"`
+	AccountType string             `bson:"accountType" json:"accountType"`
+	AccountID   primitive.ObjectID `bson:"accountID" json:"accountID"`
+	Email       string             `bson:"email" json:"email"`
+	CodeHash    string             `bson:"codeHash" json:"-"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	IsUsed      bool               `bson:"isUsed" json:"isUsed"`
+	CreateAt    primitive.DateTime `bson:"createAt" json:"createAt"`
+	ExpireAt    primitive.DateTime `bson:"expireAt" json:"expireAt"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// RefreshToken belongs to a family (one per login), rotating a token marks it used
+// and inserts its replacement into the same family
+type RefreshToken struct {
+	Id          primitive.ObjectID `bson:"_id" json:"_id"`
+	FamilyID    primitive.ObjectID `bson:"familyID" json:"familyID"`
+	UserID      primitive.ObjectID `bson:"userID" json:"userID"`
+	UserName    string             `bson:"userName" json:"userName"`
+	Role        string             `bson:"role" json:"role"`
+	AccountType string             `bson:"accountType" json:"accountType"`
+	CompanyID   string             `bson:"companyID,omitempty" json:"companyID,omitempty"`
+	MemberRole  string             `bson:"memberRole,omitempty" json:"memberRole,omitempty"`
+	TokenHash   string             `bson:"tokenHash" json:"-"`
+	// the session (family) keeps its login time, the client fields follow the
+	// device that last refreshed it
+	LoginAt    primitive.DateTime `bson:"loginAt" json:"loginAt"`
+	IP         string             `bson:"ip" json:"ip"`
+	UserAgent  string             `bson:"userAgent" json:"userAgent"`
+	LastUsedAt primitive.DateTime `bson:"lastUsedAt" json:"lastUsedAt"`
+	CreateAt   primitive.DateTime `bson:"createAt" json:"createAt"`
+	ExpireAt   primitive.DateTime `bson:"expireAt" json:"expireAt"`
+	IsUsed     bool               `bson:"isUsed" json:"isUsed"`
+	IsRevoked  bool               `bson:"isRevoked" json:"isRevoked"`
+	// set on support sessions an admin opened as this account
+	ImpersonatedBy string `bson:"impersonatedBy,omitempty" json:"impersonatedBy,omitempty"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Report flags a job post or company for the moderators
+type Report struct {
+	Id             primitive.ObjectID `bson:"_id" json:"_id"`
+	TargetType     string             `bson:"targetType" json:"targetType"`
+	TargetID       primitive.ObjectID `bson:"targetID" json:"targetID"`
+	ReporterID     primitive.ObjectID `bson:"reporterID" json:"reporterID"`
+	Reason         string             `bson:"reason" json:"reason"`
+	Detail         string             `bson:"detail,omitempty" json:"detail,omitempty"`
+	Status         string             `bson:"status" json:"status"`
+	ResolvedBy     string             `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+	ResolutionNote string             `bson:"resolutionNote,omitempty" json:"resolutionNote,omitempty"`
+	ResolvedAt     primitive.DateTime `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+	CreateAt       primitive.DateTime `bson:"createAt" json:"createAt"`
+}
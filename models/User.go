@@ -10,17 +10,23 @@ type Profile struct {
 }
 
 type User struct {
-	Id               primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	FirstName        string             `bson:"careerFirstName" json:"careerFirstName" validate:"required"`
-	LastName         string             `bson:"lastName" json:"lastName" validate:"required"`
-	CareerPhone      string             `bson:"careerPhone" json:"careerPhone" validate:"required"`
-	CareerEmail      string             `bson:"careerEmail" json:"careerEmail" validate:"required"`
-	CareerPicture    string             `bson:"careerPicture,omitempty" json:"careerPicture,omitempty"`
-	CreateAt         primitive.DateTime `bson:"createAt" json:"createAt"`
-	IsDeleted        bool               `bson:"isDeleted" json:"isDeleted"`
-	Languages        []string           `bson:"languages,omitempty" json:"languages"`
-	Password         string             `bson:"password" json:"password"`
-	Role             string             `bson:"role" json:"role"`
-	Profile          Profile            `bson:"profile" json:"profile"`
-	VerificationCode string             `bson:"verificationCode"`
+	Id            primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	FirstName     string             `bson:"careerFirstName" json:"careerFirstName" validate:"required"`
+	LastName      string             `bson:"lastName" json:"lastName" validate:"required"`
+	CareerPhone   string             `bson:"careerPhone" json:"careerPhone" validate:"required"`
+	CareerEmail   string             `bson:"careerEmail" json:"careerEmail" validate:"required"`
+	CareerPicture string             `bson:"careerPicture,omitempty" json:"careerPicture,omitempty"`
+	CreateAt      primitive.DateTime `bson:"createAt" json:"createAt"`
+	IsDeleted     bool               `bson:"isDeleted" json:"isDeleted"`
+	Languages     []string           `bson:"languages,omitempty" json:"languages"`
+	Password      string             `bson:"password" json:"password"`
+	Role          string             `bson:"role" json:"role"`
+	Profile       Profile            `bson:"profile" json:"profile"`
+	IsVerified    bool               `bson:"isVerified" json:"isVerified"`
+	VerifiedAt    primitive.DateTime `bson:"verifiedAt,omitempty" json:"verifiedAt,omitempty"`
+	IsSuspended   bool               `bson:"isSuspended,omitempty" json:"isSuspended"`
+	SuspendedAt   primitive.DateTime `bson:"suspendedAt,omitempty" json:"suspendedAt,omitempty"`
+	SuspendReason string             `bson:"suspendReason,omitempty" json:"suspendReason,omitempty"`
+	MFA           MFASettings        `bson:"mfa,omitempty" json:"-"`
+	Identities    []ExternalIdentity `bson:"identities,omitempty" json:"-"`
 }
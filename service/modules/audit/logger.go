@@ -0,0 +1,240 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"hireforwork-server/db"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/models"
+	"io"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+1. Append-only: the logger only inserts and reads, nothing updates or deletes entries
+2. Recording never fails the audited request, errors are only logged
+*/
+type Logger struct {
+	collection *mongo.Collection
+}
+
+const (
+	OutcomeSuccess = "SUCCESS"
+	OutcomeFailure = "FAILURE"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionLogin                = "LOGIN"
+	ActionLoginMFA             = "LOGIN_MFA"
+	ActionLoginOIDC            = "LOGIN_OIDC"
+	ActionLogout               = "LOGOUT"
+	ActionPasswordResetRequest = "PASSWORD_RESET_REQUEST"
+	ActionPasswordReset        = "PASSWORD_RESET"
+	ActionMFAEnable            = "MFA_ENABLE"
+	ActionMFADisable           = "MFA_DISABLE"
+	ActionAccountUnlock        = "ACCOUNT_UNLOCK"
+	ActionSessionRevoke        = "SESSION_REVOKE"
+	ActionCareerCreate         = "CAREER_CREATE"
+	ActionCareerUpdate         = "CAREER_UPDATE"
+	ActionCareerDelete         = "CAREER_DELETE"
+	ActionCompanyUpdate        = "COMPANY_UPDATE"
+	ActionCompanyDelete        = "COMPANY_DELETE"
+	ActionMemberInvite         = "MEMBER_INVITE"
+	ActionMemberRemove         = "MEMBER_REMOVE"
+	ActionAPIKeyCreate         = "API_KEY_CREATE"
+	ActionAPIKeyRevoke         = "API_KEY_REVOKE"
+	ActionJobCreate            = "JOB_CREATE"
+	ActionJobUpdate            = "JOB_UPDATE"
+	ActionJobDelete            = "JOB_DELETE"
+	ActionApplicationStatus    = "APPLICATION_STATUS_CHANGE"
+	ActionAuditExport          = "AUDIT_EXPORT"
+	ActionAccountSuspend       = "ACCOUNT_SUSPEND"
+	ActionAccountRestore       = "ACCOUNT_RESTORE"
+	ActionImpersonate          = "IMPERSONATE"
+	ActionJobClose             = "JOB_CLOSE"
+	ActionReportResolve        = "REPORT_RESOLVE"
+	ActionJobStatus            = "JOB_STATUS_CHANGE"
+	ActionJobExtend            = "JOB_EXTEND"
+)
+
+// Types of the resource an action targets
+const (
+	TargetCareer      = "CAREER"
+	TargetCompany     = "COMPANY"
+	TargetMember      = "COMPANY_MEMBER"
+	TargetAPIKey      = "API_KEY"
+	TargetJob         = "JOB"
+	TargetApplication = "APPLICATION"
+	TargetSession     = "SESSION"
+	TargetAuditLog    = "AUDIT_LOG"
+	TargetReport      = "REPORT"
+)
+
+// maxExportRows bounds a CSV export, narrow the filters to export more
+const maxExportRows = 100000
+
+var indexOnce sync.Once
+
+func NewLogger(dbInstance *db.DB) *Logger {
+	logger := &Logger{collection: dbInstance.GetCollection("AuditLog")}
+	indexOnce.Do(logger.ensureIndexes)
+	return logger
+}
+
+func (l *Logger) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := l.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"createAt", -1}}},
+		{Keys: bson.D{{"actorID", 1}, {"createAt", -1}}},
+		{Keys: bson.D{{"action", 1}, {"createAt", -1}}},
+		{Keys: bson.D{{"targetType", 1}, {"targetID", 1}, {"createAt", -1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating audit log indexes: %v", err)
+	}
+}
+
+// Record appends an entry
+func (l *Logger) Record(entry models.AuditLog) {
+	entry.Id = primitive.NewObjectID()
+	entry.CreateAt = primitive.NewDateTimeFromTime(time.Now())
+
+	if _, err := l.collection.InsertOne(context.Background(), entry); err != nil {
+		log.Printf("Error recording audit entry %s on %s %s: %v", entry.Action, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+func buildFilter(filter interfaces.IAuditFilter) bson.D {
+	bsonFilter := bson.D{}
+
+	for _, field := range []struct {
+		key   string
+		value string
+	}{
+		{"actorID", filter.ActorID},
+		{"actorRole", filter.ActorRole},
+		{"action", filter.Action},
+		{"targetType", filter.TargetType},
+		{"targetID", filter.TargetID},
+		{"outcome", filter.Outcome},
+		{"ip", filter.IP},
+	} {
+		if field.value != "" {
+			bsonFilter = append(bsonFilter, bson.E{field.key, field.value})
+		}
+	}
+
+	dateRange := bson.D{}
+	if filter.CreateFrom != "" {
+		if from, err := time.Parse("2006-01-02", filter.CreateFrom); err == nil {
+			dateRange = append(dateRange, bson.E{"$gte", primitive.NewDateTimeFromTime(from)})
+		}
+	}
+	if filter.CreateTo != "" {
+		if to, err := time.Parse("2006-01-02", filter.CreateTo); err == nil {
+			dateRange = append(dateRange, bson.E{"$lt", primitive.NewDateTimeFromTime(to.AddDate(0, 0, 1))})
+		}
+	}
+	if len(dateRange) > 0 {
+		bsonFilter = append(bsonFilter, bson.E{"createAt", dateRange})
+	}
+	return bsonFilter
+}
+
+// Query returns a page of entries matching the filter, newest first
+func (l *Logger) Query(filter interfaces.IAuditFilter) (models.PaginateDocs[models.AuditLog], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = 20
+	}
+	bsonFilter := buildFilter(filter)
+
+	totalDocs, err := l.collection.CountDocuments(context.Background(), bsonFilter)
+	if err != nil {
+		return models.PaginateDocs[models.AuditLog]{}, err
+	}
+
+	findOption := options.Find().
+		SetSort(bson.D{{"createAt", -1}}).
+		SetSkip(int64((filter.Page - 1) * filter.PageSize)).
+		SetLimit(int64(filter.PageSize))
+	cursor, err := l.collection.Find(context.Background(), bsonFilter, findOption)
+	if err != nil {
+		return models.PaginateDocs[models.AuditLog]{}, err
+	}
+	defer cursor.Close(context.Background())
+
+	entries := []models.AuditLog{}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return models.PaginateDocs[models.AuditLog]{}, err
+	}
+
+	return models.PaginateDocs[models.AuditLog]{
+		Docs:        entries,
+		TotalDocs:   totalDocs,
+		CurrentPage: int64(filter.Page),
+		TotalPage:   int64(math.Ceil(float64(totalDocs) / float64(filter.PageSize))),
+	}, nil
+}
+
+// ExportCSV streams every entry matching the filter as CSV, newest first
+func (l *Logger) ExportCSV(filter interfaces.IAuditFilter, w io.Writer) error {
+	findOption := options.Find().SetSort(bson.D{{"createAt", -1}}).SetLimit(maxExportRows)
+	cursor, err := l.collection.Find(context.Background(), buildFilter(filter), findOption)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"time", "actorId", "actorName", "actorRole", "companyId", "action", "targetType", "targetId", "ip", "userAgent", "outcome", "detail"})
+
+	for cursor.Next(context.Background()) {
+		var entry models.AuditLog
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		writer.Write([]string{
+			entry.CreateAt.Time().UTC().Format(time.RFC3339),
+			entry.ActorID,
+			csvSafe(entry.ActorName),
+			entry.ActorRole,
+			entry.CompanyID,
+			entry.Action,
+			entry.TargetType,
+			entry.TargetID,
+			entry.IP,
+			csvSafe(entry.UserAgent),
+			entry.Outcome,
+			csvSafe(entry.Detail),
+		})
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return cursor.Err()
+}
+
+// csvSafe keeps user-controlled values from being run as spreadsheet formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	apiKeyPrefix      = "hfw_"
+	apiKeyDisplayLen  = 8
+	apiKeyUsageWindow = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey   = errors.New("API key không hợp lệ hoặc đã bị thu hồi")
+	ErrInvalidScope    = errors.New("Phạm vi API key không hợp lệ")
+	ErrAPIKeyNotFound  = errors.New("Không tìm thấy API key")
+	ErrAPIKeyNameEmpty = errors.New("Tên API key không được để trống")
+)
+
+// apiKeyCache maps a key hash to its claims so that APIKeyMiddleware does not hit
+// Mongo on every ATS request, revoking a key drops its entry
+var apiKeyCache = cache.New(30*time.Second, time.Minute)
+
+// NewAPIKey is returned once on creation, the plain key cannot be read again
+type NewAPIKey struct {
+	Key    string               `json:"key"`
+	APIKey models.CompanyAPIKey `json:"apiKey"`
+}
+
+func isAPIKeyScope(scope string) bool {
+	return scope == constants.SCOPE_JOBS_READ || scope == constants.SCOPE_JOBS_WRITE || scope == constants.SCOPE_APPLICANTS_READ
+}
+
+// CreateAPIKey generates a key for the company with the given scopes
+func (a *AuthService) CreateAPIKey(companyID string, createdBy string, name string, scopes []string) (NewAPIKey, error) {
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return NewAPIKey{}, ErrAPIKeyNotFound
+	}
+	creatorObjID, _ := primitive.ObjectIDFromHex(createdBy)
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return NewAPIKey{}, ErrAPIKeyNameEmpty
+	}
+	if len(scopes) == 0 {
+		return NewAPIKey{}, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !isAPIKeyScope(scope) {
+			return NewAPIKey{}, ErrInvalidScope
+		}
+	}
+
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return NewAPIKey{}, err
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := models.CompanyAPIKey{
+		Id:        primitive.NewObjectID(),
+		CompanyID: companyObjID,
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+apiKeyDisplayLen],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		CreatedBy: creatorObjID,
+		CreateAt:  primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if _, err := a.apiKeyCollection.InsertOne(context.Background(), apiKey); err != nil {
+		return NewAPIKey{}, err
+	}
+
+	return NewAPIKey{Key: key, APIKey: apiKey}, nil
+}
+
+func (a *AuthService) ListAPIKeys(companyID string) ([]models.CompanyAPIKey, error) {
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	cursor, err := a.apiKeyCollection.Find(
+		context.Background(),
+		bson.M{"companyID": companyObjID},
+		options.Find().SetSort(bson.D{{"createAt", -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	apiKeys := []models.CompanyAPIKey{}
+	if err := cursor.All(context.Background(), &apiKeys); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// RevokeAPIKey disables a key of the company for good
+func (a *AuthService) RevokeAPIKey(companyID string, keyID string) error {
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+	keyObjID, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	var apiKey models.CompanyAPIKey
+	err = a.apiKeyCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": keyObjID, "companyID": companyObjID, "isRevoked": false},
+		bson.M{"$set": bson.M{"isRevoked": true, "revokedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	).Decode(&apiKey)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	apiKeyCache.Delete(apiKey.KeyHash)
+	return nil
+}
+
+// AuthenticateAPIKey turns an X-Api-Key header into claims acting for the company,
+// the scopes of the key then decide which routes it may call
+func (a *AuthService) AuthenticateAPIKey(key string) (*Claims, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	keyHash := utils.HashToken(key)
+
+	if cached, found := apiKeyCache.Get(keyHash); found {
+		claims := *cached.(*Claims)
+		return &claims, nil
+	}
+
+	var apiKey models.CompanyAPIKey
+	err := a.apiKeyCollection.FindOne(context.Background(), bson.M{"keyHash": keyHash, "isRevoked": false}).Decode(&apiKey)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// A deleted or suspended company loses its keys along with its logins
+	count, err := a.companyCollection.CountDocuments(context.Background(), bson.M{"_id": apiKey.CompanyID, "isDeleted": false, "isSuspended": bson.M{"$ne": true}})
+	if err != nil || count == 0 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	a.touchAPIKey(apiKey)
+
+	claims := &Claims{
+		Username:   apiKey.Name,
+		Role:       constants.COMPANY,
+		Id:         apiKey.CompanyID.Hex(),
+		CompanyID:  apiKey.CompanyID.Hex(),
+		MemberRole: constants.MEMBER_OWNER,
+		APIKeyID:   apiKey.Id.Hex(),
+		Scopes:     apiKey.Scopes,
+	}
+	claims.Subject = apiKey.CompanyID.Hex()
+
+	apiKeyCache.Set(keyHash, claims, cache.DefaultExpiration)
+	copied := *claims
+	return &copied, nil
+}
+
+// touchAPIKey records when a key was last used, at most once per window
+func (a *AuthService) touchAPIKey(apiKey models.CompanyAPIKey) {
+	now := time.Now()
+	_, err := a.apiKeyCollection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": apiKey.Id, "lastUsedAt": bson.M{"$not": bson.M{"$gt": primitive.NewDateTimeFromTime(now.Add(-apiKeyUsageWindow))}}},
+		bson.M{"$set": bson.M{"lastUsedAt": primitive.NewDateTimeFromTime(now)}},
+	)
+	if err != nil {
+		log.Printf("Error updating API key %s usage: %v", apiKey.Id.Hex(), err)
+	}
+}
@@ -4,15 +4,23 @@ import (
 	"context"
 	"errors"
 	"hireforwork-server/config"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/models"
 	"hireforwork-server/utils"
 	"log"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrEmailNotVerified = errors.New("Email chưa được xác thực, vui lòng kiểm tra hộp thư")
+	ErrAccountSuspended = errors.New("Tài khoản đã bị tạm khóa, vui lòng liên hệ quản trị viên")
 )
 
 type LoginStrategy interface {
@@ -29,37 +37,58 @@ type CompanyLoginStrategy struct {
 
 // instance for authservice
 func NewAuthService(dbInstance *db.DB) *AuthService {
-	collections := dbInstance.GetCollections([]string{"Career", "Company"})
+	collections := dbInstance.GetCollections([]string{"Career", "Company", "RefreshToken", "LoginAttempt", "CompanyMember", "CompanyAPIKey", "OIDCState"})
 
-	jwtSecret := []byte(config.GetInstance().SecretKey)
+	cfg := config.GetInstance()
+	jwtSecret := []byte(cfg.SecretKey)
 
 	if len(jwtSecret) == 0 {
 		log.Fatalf("Need a secret key")
 	}
-	return &AuthService{
-		userCollection:    collections[0],
-		companyCollection: collections[1],
-		JwtSecret:         jwtSecret,
+	authService := &AuthService{
+		userCollection:         collections[0],
+		companyCollection:      collections[1],
+		refreshTokenCollection: collections[2],
+		loginAttemptCollection: collections[3],
+		memberCollection:       collections[4],
+		apiKeyCollection:       collections[5],
+		oidcStateCollection:    collections[6],
+		JwtSecret:              jwtSecret,
+		Keys:                   loadKeySet(cfg),
+		AccessTokenTTL:         cfg.AccessTokenTTL,
+		RefreshTokenTTL:        cfg.RefreshTokenTTL,
+		RequireAdminMFA:        cfg.RequireAdminMFA,
 	}
+	indexOnce.Do(authService.ensureIndexes)
 
+	return authService
 }
 
-// Generate token
-func (a AuthService) GenerateToken(username string, id primitive.ObjectID, role string) (string, error) {
-	expirationTime := time.Now().Add(1000 * time.Minute)
+// Generate a short-lived access token bound to a session (refresh token family)
+func (a AuthService) GenerateToken(principal Principal, sessionID string) (string, error) {
+	jti, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	expirationTime := time.Now().Add(a.AccessTokenTTL)
 	claims := &Claims{
-		Username: username,
-		Role:     role,
-		Id:       id.Hex(),
-		StandardClaims: jwt.StandardClaims{
-			Subject:   id.Hex(),
-			IssuedAt:  time.Now().Unix(),
-			ExpiresAt: expirationTime.Unix(),
+		Username:       principal.Username,
+		Role:           principal.Role,
+		Id:             principal.Id.Hex(),
+		SessionID:      sessionID,
+		CompanyID:      principal.CompanyID,
+		MemberRole:     principal.MemberRole,
+		ImpersonatedBy: principal.ImpersonatedBy,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   principal.Id.Hex(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(a.JwtSecret)
+	tokenString, err := a.Keys.Sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -71,65 +100,165 @@ func (a AuthService) GenerateToken(username string, id primitive.ObjectID, role
 func (a *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		return a.JwtSecret, nil
-	})
-
-	if err != nil || !token.Valid {
+	if err := a.Keys.Parse(tokenString, claims); err != nil {
 		return nil, errors.New("Invalid token: " + err.Error())
 	}
 	return claims, nil
 }
 
-// check hashpassword
-func (a *AuthService) CheckPasswordHash(hashedPassword, password string) bool {
-	return hashedPassword == utils.EncodeToSHA(password)
+// check hashpassword, also reports whether the stored hash is outdated
+func (a *AuthService) CheckPasswordHash(hashedPassword, password string) (bool, bool) {
+	return utils.VerifyPassword(hashedPassword, password)
+}
+
+// upgrade legacy SHA3 (or outdated) hashes in place after a successful login
+func (a *AuthService) rehashPassword(collection *mongo.Collection, id primitive.ObjectID, password string) {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("Error rehashing password for %s: %v", id.Hex(), err)
+		return
+	}
+
+	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"password": hashedPassword}})
+	if err != nil {
+		log.Printf("Error upgrading password hash for %s: %v", id.Hex(), err)
+	}
 }
 
 // Login
 func (c *CareerLoginStrategy) Login(credential Credentials) (LoginResponse, error) {
 	var career models.User
 
+	attemptKeys := loginAttemptKeys(constants.CAREER, credential)
+	if err := c.authService.checkLoginAllowed(attemptKeys); err != nil {
+		return LoginResponse{}, err
+	}
+
 	err := c.authService.userCollection.FindOne(context.Background(), bson.D{
 		{"careerEmail", credential.Username},
 		{"isDeleted", false},
 	}).Decode(&career)
 	if err != nil {
-		return LoginResponse{}, errors.New("Tên đăng nhập hoặc tài khoản sai")
+		c.authService.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidCredentials
 	}
 
-	if !c.authService.CheckPasswordHash(career.Password, credential.Password) {
-		return LoginResponse{}, errors.New("Tên đăng nhập hoặc tài khoản sai")
+	match, needsRehash := c.authService.CheckPasswordHash(career.Password, credential.Password)
+	if !match {
+		c.authService.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidCredentials
 	}
-	token, _ := c.authService.GenerateToken(career.CareerEmail, career.Id, career.Role)
-
-	response := LoginResponse{
-		Token: token,
+	c.authService.resetLoginFailures(attemptKeys[0])
+	if needsRehash {
+		c.authService.rehashPassword(c.authService.userCollection, career.Id, credential.Password)
 	}
-
-	return response, nil
+	if career.IsSuspended {
+		return LoginResponse{}, ErrAccountSuspended
+	}
+	if !career.IsVerified {
+		return LoginResponse{}, ErrEmailNotVerified
+	}
+	return c.authService.finishLogin(Principal{
+		Id:       career.Id,
+		Username: career.CareerEmail,
+		Role:     NormalizeRole(career.Role),
+	}, career.MFA, credential.Client())
 }
 
+// Login signs in the company account itself (its owner) or, when the email is not a
+// company contact, one of its members
 func (co *CompanyLoginStrategy) Login(credential Credentials) (LoginResponse, error) {
 	var company models.Company
 
+	attemptKeys := loginAttemptKeys(constants.COMPANY, credential)
+	if err := co.authService.checkLoginAllowed(attemptKeys); err != nil {
+		return LoginResponse{}, err
+	}
+
 	err := co.authService.companyCollection.FindOne(context.Background(), bson.D{
 		{"contact.companyEmail", credential.Username},
 		{"isDeleted", false},
 	}).Decode(&company)
 
+	if err == mongo.ErrNoDocuments {
+		return co.loginMember(credential, attemptKeys)
+	}
+	if err != nil {
+		co.authService.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+	match, needsRehash := co.authService.CheckPasswordHash(company.Password, credential.Password)
+	if !match {
+		co.authService.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+	co.authService.resetLoginFailures(attemptKeys[0])
+	if needsRehash {
+		co.authService.rehashPassword(co.authService.companyCollection, company.Id, credential.Password)
+	}
+	if company.IsSuspended {
+		return LoginResponse{}, ErrAccountSuspended
+	}
+	if !company.IsVerified {
+		return LoginResponse{}, ErrEmailNotVerified
+	}
+	return co.authService.finishLogin(Principal{
+		Id:         company.Id,
+		Username:   company.Contact.CompanyEmail,
+		Role:       constants.COMPANY,
+		CompanyID:  company.Id.Hex(),
+		MemberRole: constants.MEMBER_OWNER,
+	}, company.MFA, credential.Client())
+}
+
+func (co *CompanyLoginStrategy) loginMember(credential Credentials, attemptKeys []attemptKey) (LoginResponse, error) {
+	var member models.CompanyMember
+
+	err := co.authService.memberCollection.FindOne(context.Background(), bson.D{
+		{"email", strings.ToLower(strings.TrimSpace(credential.Username))},
+		{"status", constants.MEMBER_ACTIVE},
+	}).Decode(&member)
+	if err != nil {
+		co.authService.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+
+	match, needsRehash := co.authService.CheckPasswordHash(member.Password, credential.Password)
+	if !match {
+		co.authService.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidCredentials
+	}
+	co.authService.resetLoginFailures(attemptKeys[0])
+	if needsRehash {
+		co.authService.rehashPassword(co.authService.memberCollection, member.Id, credential.Password)
+	}
+
+	// Members of a removed or suspended company cannot sign in
+	var company models.Company
+	err = co.authService.companyCollection.FindOne(context.Background(), bson.M{"_id": member.CompanyID, "isDeleted": false}).Decode(&company)
 	if err != nil {
-		return LoginResponse{}, errors.New("Invalid username or password")
+		return LoginResponse{}, ErrInvalidCredentials
 	}
-	if !co.authService.CheckPasswordHash(company.Password, credential.Password) {
-		return LoginResponse{}, errors.New("Wrong password")
+	if company.IsSuspended {
+		return LoginResponse{}, ErrAccountSuspended
 	}
-	token, _ := co.authService.GenerateToken(company.Contact.CompanyEmail, company.Id, "COMPANY")
 
-	response := LoginResponse{
-		Token: token,
+	return co.authService.finishLogin(Principal{
+		Id:         member.Id,
+		Username:   member.Email,
+		Role:       constants.COMPANY,
+		CompanyID:  member.CompanyID.Hex(),
+		MemberRole: member.MemberRole,
+	}, member.MFA, credential.Client())
+}
+
+// NormalizeRole maps stored roles ("Career", "") to the constants used in tokens
+func NormalizeRole(role string) string {
+	role = strings.ToUpper(strings.TrimSpace(role))
+	if role == "" {
+		return constants.CAREER
 	}
-	return response, nil
+	return role
 }
 
 // Login sử dụng Strategy Pattern
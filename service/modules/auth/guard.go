@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidCredentials is the only error a failed login reports, whatever was wrong
+var ErrInvalidCredentials = errors.New("Tên đăng nhập hoặc mật khẩu không đúng")
+
+// attemptPolicy: after delayAfter failures every attempt has to wait an exponentially
+// growing delay, after lockAfter failures the key is locked for lockFor
+type attemptPolicy struct {
+	delayAfter int
+	lockAfter  int
+	lockFor    time.Duration
+}
+
+var (
+	accountPolicy = attemptPolicy{delayAfter: 3, lockAfter: 10, lockFor: 30 * time.Minute}
+	ipPolicy      = attemptPolicy{delayAfter: 20, lockAfter: 50, lockFor: 15 * time.Minute}
+)
+
+const (
+	maxLoginDelay = time.Minute
+	// failures are forgotten after a day without new ones
+	attemptRetention = 24 * time.Hour
+)
+
+type attemptKey struct {
+	id     string
+	policy attemptPolicy
+}
+
+type loginAttempt struct {
+	Key           string    `bson:"_id"`
+	Failures      int       `bson:"failures"`
+	LastFailureAt time.Time `bson:"lastFailureAt"`
+	LockedUntil   time.Time `bson:"lockedUntil"`
+	ExpireAt      time.Time `bson:"expireAt"`
+}
+
+// LoginLockedError is returned while an account or client IP has to wait before retrying
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("Đăng nhập sai quá nhiều lần, vui lòng thử lại sau %d giây", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+func accountAttemptKey(accountType string, username string) attemptKey {
+	return attemptKey{
+		id:     "account:" + accountType + ":" + strings.ToLower(strings.TrimSpace(username)),
+		policy: accountPolicy,
+	}
+}
+
+func loginAttemptKeys(accountType string, credential Credentials) []attemptKey {
+	keys := []attemptKey{accountAttemptKey(accountType, credential.Username)}
+	if credential.IP != "" {
+		keys = append(keys, attemptKey{id: "ip:" + credential.IP, policy: ipPolicy})
+	}
+	return keys
+}
+
+func (p attemptPolicy) delay(failures int) time.Duration {
+	if failures < p.delayAfter {
+		return 0
+	}
+	shift := failures - p.delayAfter
+	if shift > 6 {
+		return maxLoginDelay
+	}
+	delay := time.Second << shift
+	if delay > maxLoginDelay {
+		return maxLoginDelay
+	}
+	return delay
+}
+
+// checkLoginAllowed returns a LoginLockedError while any key is locked or delayed
+func (a *AuthService) checkLoginAllowed(keys []attemptKey) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		var attempt loginAttempt
+		if err := a.loginAttemptCollection.FindOne(context.Background(), bson.M{"_id": key.id}).Decode(&attempt); err != nil {
+			continue
+		}
+
+		wait := attempt.LockedUntil.Sub(now)
+		if delayed := attempt.LastFailureAt.Add(key.policy.delay(attempt.Failures)).Sub(now); delayed > wait {
+			wait = delayed
+		}
+		if wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failure on every key and locks the ones over their limit
+func (a *AuthService) recordLoginFailure(keys []attemptKey) {
+	now := time.Now()
+
+	for _, key := range keys {
+		var attempt loginAttempt
+		err := a.loginAttemptCollection.FindOneAndUpdate(
+			context.Background(),
+			bson.M{"_id": key.id},
+			bson.M{
+				"$inc": bson.M{"failures": 1},
+				"$set": bson.M{"lastFailureAt": now, "expireAt": now.Add(attemptRetention)},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&attempt)
+		if err != nil {
+			log.Printf("Error recording login failure for %s: %v", key.id, err)
+			continue
+		}
+
+		if attempt.Failures >= key.policy.lockAfter {
+			lockedUntil := now.Add(key.policy.lockFor)
+			_, err := a.loginAttemptCollection.UpdateOne(
+				context.Background(),
+				bson.M{"_id": key.id},
+				bson.M{"$set": bson.M{
+					"failures":    0,
+					"lockedUntil": lockedUntil,
+					"expireAt":    lockedUntil.Add(attemptRetention),
+				}},
+			)
+			if err != nil {
+				log.Printf("Error locking %s: %v", key.id, err)
+			}
+		}
+	}
+}
+
+func (a *AuthService) resetLoginFailures(key attemptKey) {
+	if _, err := a.loginAttemptCollection.DeleteOne(context.Background(), bson.M{"_id": key.id}); err != nil {
+		log.Printf("Error resetting login failures for %s: %v", key.id, err)
+	}
+}
+
+// UnlockAccount clears the failed attempts and lockout of an account
+func (a *AuthService) UnlockAccount(accountType string, accountID string) error {
+	_id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return errors.New("Invalid account ID")
+	}
+
+	principal, _, _, err := a.loadAccount(accountType, _id)
+	if err != nil {
+		return errors.New("Không tìm thấy tài khoản")
+	}
+
+	a.resetLoginFailures(accountAttemptKey(accountType, principal.Username))
+	return nil
+}
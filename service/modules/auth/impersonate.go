@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrCannotImpersonate = errors.New("Không thể truy cập thay tài khoản này")
+
+// impersonationTTL bounds a support session, it cannot be refreshed
+const impersonationTTL = 30 * time.Minute
+
+// Impersonate opens a short support session as a career or company account. The
+// session has no refresh token, shows up in the account's session list and every
+// access token names the admin, so whatever support does stays attributable.
+func (a *AuthService) Impersonate(admin *Claims, accountType string, accountID string, client ClientInfo) (LoginResponse, error) {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return LoginResponse{}, ErrCannotImpersonate
+	}
+	principal, _, _, err := a.loadAccount(accountType, id)
+	if err != nil {
+		return LoginResponse{}, ErrCannotImpersonate
+	}
+	// Support never escalates to another admin
+	if principal.Role == constants.ADMIN {
+		return LoginResponse{}, ErrCannotImpersonate
+	}
+	principal.ImpersonatedBy = admin.Subject
+
+	// The session still needs a token hash, it is simply never handed out
+	unusedToken, err := utils.RandomToken(32)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	now := time.Now()
+	familyID := primitive.NewObjectID()
+	record := models.RefreshToken{
+		Id:             primitive.NewObjectID(),
+		FamilyID:       familyID,
+		UserID:         principal.Id,
+		UserName:       principal.Username,
+		Role:           principal.Role,
+		AccountType:    accountType,
+		CompanyID:      principal.CompanyID,
+		MemberRole:     principal.MemberRole,
+		TokenHash:      utils.HashToken(unusedToken),
+		LoginAt:        primitive.NewDateTimeFromTime(now),
+		IP:             client.IP,
+		UserAgent:      client.UserAgent,
+		LastUsedAt:     primitive.NewDateTimeFromTime(now),
+		CreateAt:       primitive.NewDateTimeFromTime(now),
+		ExpireAt:       primitive.NewDateTimeFromTime(now.Add(impersonationTTL)),
+		ImpersonatedBy: admin.Subject,
+	}
+	if _, err := a.refreshTokenCollection.InsertOne(context.Background(), record); err != nil {
+		return LoginResponse{}, err
+	}
+
+	// IsSessionRevoked rejects the access token once the session expires, even when
+	// the token itself is configured to live longer
+	token, err := a.GenerateToken(principal, familyID.Hex())
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	expiresIn := a.AccessTokenTTL
+	if expiresIn > impersonationTTL {
+		expiresIn = impersonationTTL
+	}
+	return LoginResponse{
+		Token:     token,
+		ExpiresIn: int64(expiresIn.Seconds()),
+	}, nil
+}
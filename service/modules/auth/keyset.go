@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hireforwork-server/config"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKeyID = errors.New("Unknown signing key")
+)
+
+// signingKey is one entry of the keyset, retired keys only keep their public part
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// KeySet signs access tokens with the active key and verifies them with any key
+// still listed, so a rotation does not invalidate tokens already issued
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+	// verifies tokens issued before kid headers existed until legacyUntil, nil once
+	// disabled
+	legacySecret []byte
+	legacyUntil  time.Time
+}
+
+// JWK is the public part of a key as published by the JWKS endpoint
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var (
+	keySet     *KeySet
+	keySetOnce sync.Once
+)
+
+// loadKeySet reads the keyset once for the process. Without JWT_KEYS_DIR tokens
+// keep being signed with HS256 and SECRET_KEY.
+func loadKeySet(cfg *config.Config) *KeySet {
+	keySetOnce.Do(func() {
+		var err error
+		keySet, err = newKeySet(cfg)
+		if err != nil {
+			log.Fatalf("Error loading JWT keys: %v", err)
+		}
+	})
+	return keySet
+}
+
+func newKeySet(cfg *config.Config) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*signingKey{}}
+	if time.Now().Before(cfg.LegacyTokensUntil) {
+		ks.legacySecret = []byte(cfg.SecretKey)
+		ks.legacyUntil = cfg.LegacyTokensUntil
+	}
+
+	if cfg.JwtKeysDir == "" {
+		sum := sha256.Sum256([]byte(cfg.SecretKey))
+		ks.active = &signingKey{
+			kid:     "hs256-" + hex.EncodeToString(sum[:4]),
+			method:  jwt.SigningMethodHS256,
+			private: []byte(cfg.SecretKey),
+			public:  []byte(cfg.SecretKey),
+		}
+		ks.keys[ks.active.kid] = ks.active
+		return ks, nil
+	}
+
+	// Every <kid>.pem holds a private key, <kid>.pub.pem a retired public key
+	files, err := filepath.Glob(filepath.Join(cfg.JwtKeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		key, err := readKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		// A key still holding its private part wins over a leftover <kid>.pub.pem,
+		// whatever order the files are listed in
+		if existing, ok := ks.keys[key.kid]; ok && existing.private != nil {
+			continue
+		}
+		ks.keys[key.kid] = key
+	}
+
+	active, ok := ks.keys[cfg.JwtActiveKid]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key in %s", cfg.JwtActiveKid, cfg.JwtKeysDir)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+func readKeyFile(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+
+	name := filepath.Base(file)
+	key := &signingKey{kid: strings.TrimSuffix(strings.TrimSuffix(name, ".pem"), ".pub")}
+
+	if strings.HasSuffix(name, ".pub.pem") {
+		key.public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	} else {
+		var private interface{}
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		}
+		key.private = private
+		if signer, ok := private.(crypto.Signer); ok {
+			key.public = signer.Public()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, errors.New("only RSA and Ed25519 keys are supported")
+	}
+	return key, nil
+}
+
+// Sign issues a token with the active key and its kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	token.Header["kid"] = ks.active.kid
+	return token.SignedString(ks.active.private)
+}
+
+// Parse verifies a token against the key named in its kid header, the algorithm
+// must be the one of that key
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" && ks.legacySecret != nil && t.Method == jwt.SigningMethodHS256 && time.Now().Before(ks.legacyUntil) {
+			return ks.legacySecret, nil
+		}
+
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKeyID
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return jwt.ErrTokenInvalidClaims
+	}
+	return nil
+}
+
+// JWKS lists the public keys other services can verify our tokens with,
+// HS256 keys are secret and never published
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for kid, key := range ks.keys {
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"hireforwork-server/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	activeKid  = "2026-active"
+	retiredKid = "2025-retired"
+	testSecret = "legacy-secret"
+)
+
+// testKeys are the keys written to a JWT_KEYS_DIR: the active RSA key, a leftover
+// public copy of it, and a retired Ed25519 key of which only the public part remains
+type testKeys struct {
+	dir     string
+	active  *rsa.PrivateKey
+	retired ed25519.PrivateKey
+}
+
+func writePEM(t *testing.T, file string, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	keys := testKeys{dir: t.TempDir()}
+
+	var err error
+	keys.active, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := x509.MarshalPKCS8PrivateKey(keys.active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(keys.dir, activeKid+".pem"), "PRIVATE KEY", private)
+	activePublic, err := x509.MarshalPKIXPublicKey(&keys.active.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(keys.dir, activeKid+".pub.pem"), "PUBLIC KEY", activePublic)
+
+	var retiredPublic ed25519.PublicKey
+	retiredPublic, keys.retired, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(retiredPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(keys.dir, retiredKid+".pub.pem"), "PUBLIC KEY", public)
+	return keys
+}
+
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestNewKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		wantErr   bool
+		wantKid   string
+		wantAlg   string
+		wantCount int
+	}{
+		{"secret only", config.Config{SecretKey: testSecret}, false, "", "HS256", 1},
+		{"keys dir", config.Config{SecretKey: testSecret, JwtKeysDir: keys.dir, JwtActiveKid: activeKid}, false, activeKid, "RS256", 2},
+		{"active key without private part", config.Config{SecretKey: testSecret, JwtKeysDir: keys.dir, JwtActiveKid: retiredKid}, true, "", "", 0},
+		{"unknown active key", config.Config{SecretKey: testSecret, JwtKeysDir: keys.dir, JwtActiveKid: "missing"}, true, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := newKeySet(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newKeySet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantKid != "" && ks.active.kid != tt.wantKid {
+				t.Errorf("active kid = %q, want %q", ks.active.kid, tt.wantKid)
+			}
+			if ks.active.method.Alg() != tt.wantAlg || ks.active.private == nil {
+				t.Errorf("active key = %s without private part", ks.active.method.Alg())
+			}
+			if len(ks.keys) != tt.wantCount {
+				t.Errorf("%d keys, want %d", len(ks.keys), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestKeySetParse(t *testing.T) {
+	keys := newTestKeys(t)
+	now := time.Now()
+	valid := jwt.RegisteredClaims{Subject: "career", ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute))}
+	expired := jwt.RegisteredClaims{Subject: "career", ExpiresAt: jwt.NewNumericDate(now.Add(-time.Minute))}
+	noExpiry := jwt.RegisteredClaims{Subject: "career"}
+
+	ks, err := newKeySet(&config.Config{SecretKey: testSecret, JwtKeysDir: keys.dir, JwtActiveKid: activeKid, LegacyTokensUntil: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, err := newKeySet(&config.Config{SecretKey: testSecret, JwtKeysDir: keys.dir, JwtActiveKid: activeKid, LegacyTokensUntil: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := ks.Sign(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, payload, _ := strings.Cut(signed, ".")
+	otherPayload := strings.SplitN(signWith(t, jwt.SigningMethodRS256, activeKid, keys.active, jwt.RegisteredClaims{Subject: "admin", ExpiresAt: valid.ExpiresAt}), ".", 3)[1]
+	activePublic, _ := os.ReadFile(filepath.Join(keys.dir, activeKid+".pub.pem"))
+
+	tests := []struct {
+		name    string
+		keySet  *KeySet
+		token   string
+		wantErr error
+	}{
+		{"active key", ks, signed, nil},
+		{"retired key", ks, signWith(t, jwt.SigningMethodEdDSA, retiredKid, keys.retired, valid), nil},
+		{"legacy token in the window", ks, signWith(t, jwt.SigningMethodHS256, "", []byte(testSecret), valid), nil},
+		{"legacy token after the window", closed, signWith(t, jwt.SigningMethodHS256, "", []byte(testSecret), valid), ErrUnknownKeyID},
+		{"legacy token with another secret", ks, signWith(t, jwt.SigningMethodHS256, "", []byte("guess"), valid), jwt.ErrTokenSignatureInvalid},
+		{"unknown kid", ks, signWith(t, jwt.SigningMethodRS256, "other", keys.active, valid), ErrUnknownKeyID},
+		{"algorithm of another key", ks, signWith(t, jwt.SigningMethodRS256, retiredKid, keys.active, valid), jwt.ErrTokenSignatureInvalid},
+		{"public key as HMAC secret", ks, signWith(t, jwt.SigningMethodHS256, activeKid, activePublic, valid), jwt.ErrTokenSignatureInvalid},
+		{"none algorithm", ks, signWith(t, jwt.SigningMethodNone, activeKid, jwt.UnsafeAllowNoneSignatureType, valid), jwt.ErrTokenSignatureInvalid},
+		{"expired", ks, signWith(t, jwt.SigningMethodRS256, activeKid, keys.active, expired), jwt.ErrTokenExpired},
+		{"no expiry", ks, signWith(t, jwt.SigningMethodRS256, activeKid, keys.active, noExpiry), jwt.ErrTokenRequiredClaimMissing},
+		{"tampered payload", ks, header + "." + otherPayload + "." + strings.SplitN(signed, ".", 3)[2], jwt.ErrTokenSignatureInvalid},
+		{"not a token", ks, payload, jwt.ErrTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &jwt.RegisteredClaims{}
+			err := tt.keySet.Parse(tt.token, claims)
+			if tt.wantErr == nil {
+				if err != nil || claims.Subject != "career" {
+					t.Fatalf("Parse() = %v, subject %q", err, claims.Subject)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Parse() accepted the token")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mfaChallengePurpose = "mfa-challenge"
+	mfaEnrollPurpose    = "mfa-enroll"
+	mfaTokenTTL         = 5 * time.Minute
+	totpIssuer          = "HireForWork"
+	recoveryCodeCount   = 10
+	// wrong codes accepted for a pending secret before enrollment has to start over
+	maxEnrollFailures = 5
+)
+
+var (
+	ErrInvalidMFAToken   = errors.New("Phiên xác thực hai bước không hợp lệ hoặc đã hết hạn")
+	ErrInvalidMFACode    = errors.New("Mã xác thực không đúng")
+	ErrMFAAlreadyEnabled = errors.New("Xác thực hai bước đã được bật")
+	ErrMFANotPending     = errors.New("Chưa bắt đầu đăng ký xác thực hai bước")
+	ErrMFARequired       = errors.New("Tài khoản quản trị bắt buộc phải bật xác thực hai bước")
+)
+
+// MFAEnrollment is shown once to the user to add the account to an authenticator app
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+// MFAConfirmation returns the recovery codes, plus a token pair when enrollment
+// completed a login that was blocked by the ADMIN policy
+type MFAConfirmation struct {
+	RecoveryCodes []string       `json:"recoveryCodes"`
+	Login         *LoginResponse `json:"login,omitempty"`
+}
+
+// finishLogin is the last step of every LoginStrategy once the password matched:
+// accounts with TOTP get a challenge instead of tokens
+func (a *AuthService) finishLogin(principal Principal, mfa models.MFASettings, client ClientInfo) (LoginResponse, error) {
+	subject := accountType(principal.Role) + ":" + principal.Id.Hex()
+
+	if mfa.Enabled {
+		return LoginResponse{
+			MFARequired: true,
+			MFAToken:    utils.SignToken(a.JwtSecret, mfaChallengePurpose, subject, mfaTokenTTL),
+		}, nil
+	}
+	if principal.Role == constants.ADMIN && a.RequireAdminMFA {
+		return LoginResponse{
+			MFAEnrollmentRequired: true,
+			MFAToken:              utils.SignToken(a.JwtSecret, mfaEnrollPurpose, subject, mfaTokenTTL),
+		}, nil
+	}
+	return a.IssueTokens(principal, client)
+}
+
+// loadAccount rebuilds the principal and MFA settings of an account, along with the
+// collection it lives in since company logins can be members
+func (a *AuthService) loadAccount(accountType string, id primitive.ObjectID) (Principal, models.MFASettings, *mongo.Collection, error) {
+	filter := bson.M{"_id": id, "isDeleted": false, "isSuspended": bson.M{"$ne": true}}
+
+	if accountType == constants.COMPANY {
+		var company models.Company
+		err := a.companyCollection.FindOne(context.Background(), filter).Decode(&company)
+		if err == nil {
+			return Principal{
+				Id:         company.Id,
+				Username:   company.Contact.CompanyEmail,
+				Role:       constants.COMPANY,
+				CompanyID:  company.Id.Hex(),
+				MemberRole: constants.MEMBER_OWNER,
+			}, company.MFA, a.companyCollection, nil
+		}
+
+		var member models.CompanyMember
+		err = a.memberCollection.FindOne(context.Background(), bson.M{"_id": id, "status": constants.MEMBER_ACTIVE}).Decode(&member)
+		if err != nil {
+			return Principal{}, models.MFASettings{}, nil, err
+		}
+		// Members go with their company
+		companyFilter := bson.M{"_id": member.CompanyID, "isDeleted": false, "isSuspended": bson.M{"$ne": true}}
+		if err := a.companyCollection.FindOne(context.Background(), companyFilter).Err(); err != nil {
+			return Principal{}, models.MFASettings{}, nil, err
+		}
+		return Principal{
+			Id:         member.Id,
+			Username:   member.Email,
+			Role:       constants.COMPANY,
+			CompanyID:  member.CompanyID.Hex(),
+			MemberRole: member.MemberRole,
+		}, member.MFA, a.memberCollection, nil
+	}
+
+	var career models.User
+	if err := a.userCollection.FindOne(context.Background(), filter).Decode(&career); err != nil {
+		return Principal{}, models.MFASettings{}, nil, err
+	}
+	return Principal{Id: career.Id, Username: career.CareerEmail, Role: NormalizeRole(career.Role)}, career.MFA, a.userCollection, nil
+}
+
+// resolveMFAToken returns the account of a challenge or enrollment token
+func (a *AuthService) resolveMFAToken(token, purpose, expectedAccountType string) (primitive.ObjectID, error) {
+	subject, err := utils.VerifySignedToken(a.JwtSecret, token, purpose)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidMFAToken
+	}
+
+	tokenAccountType, id, found := strings.Cut(subject, ":")
+	if !found || tokenAccountType != expectedAccountType {
+		return primitive.NilObjectID, ErrInvalidMFAToken
+	}
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidMFAToken
+	}
+	return _id, nil
+}
+
+// ResolveEnrollmentToken lets an ADMIN blocked by the MFA policy enroll before login
+func (a *AuthService) ResolveEnrollmentToken(token, expectedAccountType string) (string, error) {
+	id, err := a.resolveMFAToken(token, mfaEnrollPurpose, expectedAccountType)
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+// VerifyMFALogin completes a two-step login with a TOTP or recovery code
+func (a *AuthService) VerifyMFALogin(mfaToken, code, expectedAccountType string, client ClientInfo) (LoginResponse, error) {
+	id, err := a.resolveMFAToken(mfaToken, mfaChallengePurpose, expectedAccountType)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	principal, mfa, collection, err := a.loadAccount(expectedAccountType, id)
+	if err != nil || !mfa.Enabled {
+		return LoginResponse{}, ErrInvalidMFAToken
+	}
+
+	// Wrong codes count against the same lockout as wrong passwords
+	attemptKeys := []attemptKey{accountAttemptKey(expectedAccountType, principal.Username)}
+	if err := a.checkLoginAllowed(attemptKeys); err != nil {
+		return LoginResponse{}, err
+	}
+	if !a.consumeMFACode(collection, id, mfa, code) {
+		a.recordLoginFailure(attemptKeys)
+		return LoginResponse{}, ErrInvalidMFACode
+	}
+	a.resetLoginFailures(attemptKeys[0])
+
+	return a.IssueTokens(principal, client)
+}
+
+// consumeMFACode accepts a TOTP code of a step not used before, or an unused recovery code
+func (a *AuthService) consumeMFACode(collection *mongo.Collection, id primitive.ObjectID, mfa models.MFASettings, code string) bool {
+	code = strings.TrimSpace(code)
+
+	secret, err := utils.DecryptSecret(a.JwtSecret, mfa.Secret)
+	if err == nil {
+		if step, ok := utils.ValidateTOTP(secret, code, time.Now()); ok {
+			result, err := collection.UpdateOne(
+				context.Background(),
+				bson.M{"_id": id, "mfa.lastUsedStep": bson.M{"$not": bson.M{"$gte": step}}},
+				bson.M{"$set": bson.M{"mfa.lastUsedStep": step}},
+			)
+			return err == nil && result.ModifiedCount == 1
+		}
+	}
+
+	codeHash := utils.HashToken(normalizeRecoveryCode(code))
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id, "mfa.recoveryCodes": codeHash},
+		bson.M{"$pull": bson.M{"mfa.recoveryCodes": codeHash}},
+	)
+	return err == nil && result.ModifiedCount == 1
+}
+
+// BeginMFAEnrollment stores a pending secret until it is confirmed with a first code
+func (a *AuthService) BeginMFAEnrollment(accountType string, accountID string) (MFAEnrollment, error) {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return MFAEnrollment{}, ErrInvalidMFAToken
+	}
+	principal, mfa, collection, err := a.loadAccount(accountType, id)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if mfa.Enabled {
+		return MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	encrypted, err := utils.EncryptSecret(a.JwtSecret, secret)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	_, err = collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{
+			"$set":   bson.M{"mfa.pendingSecret": encrypted},
+			"$unset": bson.M{"mfa.pendingFailures": ""},
+		},
+	)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	return MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, principal.Username, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment enables TOTP once the user proves the app is set up and
+// returns the recovery codes, which are only stored hashed
+func (a *AuthService) ConfirmMFAEnrollment(accountType string, accountID string, code string) ([]string, error) {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	principal, mfa, collection, err := a.loadAccount(accountType, id)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if mfa.PendingSecret == "" {
+		return nil, ErrMFANotPending
+	}
+
+	// Wrong codes count against the same lockout as wrong passwords
+	attemptKeys := []attemptKey{accountAttemptKey(accountType, principal.Username)}
+	if err := a.checkLoginAllowed(attemptKeys); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.DecryptSecret(a.JwtSecret, mfa.PendingSecret)
+	if err != nil {
+		return nil, ErrMFANotPending
+	}
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		a.recordLoginFailure(attemptKeys)
+		a.recordEnrollFailure(collection, id, mfa.PendingSecret)
+		return nil, ErrInvalidMFACode
+	}
+	a.resetLoginFailures(attemptKeys[0])
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"mfa": models.MFASettings{
+			Enabled:       true,
+			Secret:        mfa.PendingSecret,
+			RecoveryCodes: hashes,
+			LastUsedStep:  step,
+			EnabledAt:     primitive.NewDateTimeFromTime(time.Now()),
+		}}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// recordEnrollFailure counts a wrong code against the pending secret and drops the
+// secret once it reaches maxEnrollFailures, so it cannot be guessed at leisure
+func (a *AuthService) recordEnrollFailure(collection *mongo.Collection, id primitive.ObjectID, pendingSecret string) {
+	var account struct {
+		MFA models.MFASettings `bson:"mfa"`
+	}
+	err := collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": id, "mfa.pendingSecret": pendingSecret},
+		bson.M{"$inc": bson.M{"mfa.pendingFailures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"mfa.pendingFailures": 1}),
+	).Decode(&account)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Error recording MFA enrollment failure for %s: %v", id.Hex(), err)
+		}
+		return
+	}
+
+	if account.MFA.PendingFailures >= maxEnrollFailures {
+		_, err := collection.UpdateOne(
+			context.Background(),
+			bson.M{"_id": id, "mfa.pendingSecret": pendingSecret},
+			bson.M{"$unset": bson.M{"mfa.pendingSecret": "", "mfa.pendingFailures": ""}},
+		)
+		if err != nil {
+			log.Printf("Error dropping pending MFA secret for %s: %v", id.Hex(), err)
+		}
+	}
+}
+
+// IssueTokensFor starts a session for an account that completed MFA enrollment
+func (a *AuthService) IssueTokensFor(accountType string, accountID string, client ClientInfo) (LoginResponse, error) {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return LoginResponse{}, ErrInvalidMFAToken
+	}
+	principal, _, _, err := a.loadAccount(accountType, id)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	return a.IssueTokens(principal, client)
+}
+
+// DisableMFA turns TOTP off after checking a current code
+func (a *AuthService) DisableMFA(accountType string, accountID string, code string) error {
+	id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return ErrInvalidMFAToken
+	}
+	principal, mfa, collection, err := a.loadAccount(accountType, id)
+	if err != nil {
+		return err
+	}
+	if principal.Role == constants.ADMIN && a.RequireAdminMFA {
+		return ErrMFARequired
+	}
+	if !mfa.Enabled || !a.consumeMFACode(collection, id, mfa, code) {
+		return ErrInvalidMFACode
+	}
+
+	_, err = collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"mfa": models.MFASettings{}}},
+	)
+	return err
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = utils.HashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
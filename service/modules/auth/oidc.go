@@ -0,0 +1,518 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hireforwork-server/config"
+	"hireforwork-server/constants"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	oidcStateTTL = 10 * time.Minute
+	// the state cookie binds a login to the browser that started it
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/careers/auth/oidc"
+	// provider keys are refetched at most this often, or when a kid is unknown
+	oidcKeysRefresh = 5 * time.Minute
+)
+
+var (
+	ErrOIDCNotConfigured    = errors.New("Đăng nhập bằng tài khoản bên ngoài chưa được cấu hình")
+	ErrInvalidOIDCState     = errors.New("Phiên đăng nhập bên ngoài không hợp lệ hoặc đã hết hạn")
+	ErrOIDCEmailNotVerified = errors.New("Email của tài khoản bên ngoài chưa được xác thực")
+	ErrInvalidIDToken       = errors.New("ID token không hợp lệ")
+	ErrOIDCLinkRefused      = errors.New("Tài khoản này không thể liên kết với đăng nhập bên ngoài")
+)
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// oidcClaims are the ID token claims used to find or create the career
+type oidcClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+	Name          string      `json:"name"`
+	Picture       string      `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// emailVerified accepts both booleans and the "true" string some providers send
+func (c *oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// OIDCLoginStrategy signs careers in with a generic OpenID Connect provider using
+// the authorization code flow with PKCE. The provider is discovered from its issuer
+// URL, so any compliant issuer (including a local mock) can be configured.
+type OIDCLoginStrategy struct {
+	authService  *AuthService
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	HTTPClient   *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewOIDCLoginStrategy returns nil when no provider is configured
+func NewOIDCLoginStrategy(authService *AuthService) *OIDCLoginStrategy {
+	cfg := config.GetInstance()
+	if cfg.OIDCIssuerURL == "" || cfg.OIDCClientID == "" {
+		return nil
+	}
+	return &OIDCLoginStrategy{
+		authService:  authService,
+		issuer:       cfg.OIDCIssuerURL,
+		clientID:     cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		redirectURL:  cfg.OIDCRedirectURL,
+		scopes:       cfg.OIDCScopes,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthorizationURL starts a login: the state, PKCE verifier and nonce are kept
+// server side and the user is sent to the returned provider URL. The state is returned
+// as well so the caller can bind it to the browser with StateCookie.
+func (o *OIDCLoginStrategy) AuthorizationURL() (string, string, error) {
+	discovery, err := o.getDiscovery()
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.RandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = o.authService.oidcStateCollection.InsertOne(context.Background(), models.OIDCState{
+		Id:       utils.HashToken(state),
+		Verifier: verifier,
+		Nonce:    nonce,
+		ExpireAt: primitive.NewDateTimeFromTime(time.Now().Add(oidcStateTTL)),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return o.authorizationURL(discovery, state, verifier, nonce), state, nil
+}
+
+// authorizationURL sends the user to the provider with the state, nonce and the S256
+// challenge of the PKCE verifier
+func (o *OIDCLoginStrategy) authorizationURL(discovery *oidcDiscovery, state, verifier, nonce string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.clientID},
+		"redirect_uri":          {o.redirectURL},
+		"scope":                 {strings.Join(o.scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// StateCookie keeps the hash of state in the browser starting the login, a callback
+// coming from any other browser is refused (login CSRF)
+func (o *OIDCLoginStrategy) StateCookie(state string) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    utils.HashToken(state),
+		Path:     oidcCookiePath,
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(o.redirectURL, "https://"),
+		// sent along the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ClearStateCookie drops the state cookie once the callback has been handled
+func (o *OIDCLoginStrategy) ClearStateCookie() *http.Cookie {
+	cookie := o.StateCookie("")
+	cookie.Value = ""
+	cookie.MaxAge = -1
+	return cookie
+}
+
+// StateMatchesCookie reports whether state is the one started by the browser of r
+func (o *OIDCLoginStrategy) StateMatchesCookie(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(utils.HashToken(state))) == 1
+}
+
+// Login completes the flow with the code and state the provider redirected back with
+func (o *OIDCLoginStrategy) Login(credential Credentials) (LoginResponse, error) {
+	if credential.Code == "" || credential.State == "" {
+		return LoginResponse{}, ErrInvalidOIDCState
+	}
+
+	// The state is single-use: it is deleted as it is read
+	var state models.OIDCState
+	err := o.authService.oidcStateCollection.FindOneAndDelete(
+		context.Background(),
+		bson.M{"_id": utils.HashToken(credential.State), "expireAt": bson.M{"$gt": time.Now()}},
+	).Decode(&state)
+	if err != nil {
+		return LoginResponse{}, ErrInvalidOIDCState
+	}
+
+	idToken, err := o.exchangeCode(credential.Code, state.Verifier)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	claims, err := o.verifyIDToken(idToken, state.Nonce)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	career, err := o.findOrCreateCareer(claims)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if career.IsSuspended {
+		return LoginResponse{}, ErrAccountSuspended
+	}
+
+	return o.authService.finishLogin(Principal{
+		Id:       career.Id,
+		Username: career.CareerEmail,
+		Role:     NormalizeRole(career.Role),
+	}, career.MFA, credential.Client())
+}
+
+func (o *OIDCLoginStrategy) getDiscovery() (*oidcDiscovery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := o.getJSON(o.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery: %w", err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != o.issuer || discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, errors.New("OIDC discovery: incomplete or mismatched provider metadata")
+	}
+	o.discovery = &discovery
+	return o.discovery, nil
+}
+
+func (o *OIDCLoginStrategy) getJSON(endpoint string, target interface{}) error {
+	resp, err := o.HTTPClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+func (o *OIDCLoginStrategy) exchangeCode(code, verifier string) (string, error) {
+	discovery, err := o.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.redirectURL},
+		"client_id":     {o.clientID},
+		"code_verifier": {verifier},
+	}
+	if o.clientSecret != "" {
+		form.Set("client_secret", o.clientSecret)
+	}
+
+	resp, err := o.HTTPClient.PostForm(discovery.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("OIDC token exchange: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.IDToken == "" {
+		return "", fmt.Errorf("OIDC token exchange: %s %s", token.Error, token.ErrorDescription)
+	}
+	return token.IDToken, nil
+}
+
+func (o *OIDCLoginStrategy) verifyIDToken(idToken, nonce string) (*oidcClaims, error) {
+	discovery, err := o.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &oidcClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return o.publicKey(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(o.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+	if claims.Nonce != nonce || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+	return claims, nil
+}
+
+// publicKey returns the provider key of a kid, refetching the provider JWKS when
+// the kid is unknown since providers rotate their keys
+func (o *OIDCLoginStrategy) publicKey(kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	key, ok := o.keys[kid]
+	stale := time.Since(o.keysFetchedAt) > oidcKeysRefresh
+	o.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, ErrUnknownKeyID
+	}
+
+	discovery, err := o.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	var jwks JWKS
+	if err := o.getJSON(discovery.JwksURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if publicKey, err := jwk.PublicKey(); err == nil {
+			keys[jwk.Kid] = publicKey
+		}
+	}
+
+	o.mu.Lock()
+	o.keys = keys
+	o.keysFetchedAt = time.Now()
+	o.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// PublicKey decodes an RSA, P-256 or Ed25519 JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errors.New("unsupported key type " + k.Kty)
+}
+
+// findOrCreateCareer returns the career linked to the provider subject, links an
+// existing career with the same verified email, or registers a new one
+func (o *OIDCLoginStrategy) findOrCreateCareer(claims *oidcClaims) (models.User, error) {
+	users := o.authService.userCollection
+	identity := bson.M{"identities.issuer": o.issuer, "identities.subject": claims.Subject}
+
+	var career models.User
+	err := users.FindOne(context.Background(), bson.M{"$and": []bson.M{identity, {"isDeleted": false}}}).Decode(&career)
+	if err == nil {
+		return career, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.User{}, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !claims.emailVerified() {
+		return models.User{}, ErrOIDCEmailNotVerified
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	link := models.ExternalIdentity{Issuer: o.issuer, Subject: claims.Subject, LinkedAt: now}
+
+	var existing models.User
+	err = users.FindOne(
+		context.Background(),
+		bson.M{"careerEmail": bson.M{"$regex": "^" + regexp.QuoteMeta(email) + "$", "$options": "i"}, "isDeleted": false},
+	).Decode(&existing)
+	if err == nil {
+		update, revoke, err := linkIdentityUpdate(existing, link)
+		if err != nil {
+			return models.User{}, err
+		}
+		// Only the career as it was read is linked, a concurrent verification makes
+		// the login fail rather than apply a stale decision
+		filter := bson.M{"_id": existing.Id, "isDeleted": false, "isVerified": existing.IsVerified}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := users.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&career); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return models.User{}, ErrOIDCLinkRefused
+			}
+			return models.User{}, err
+		}
+		if revoke {
+			o.authService.RevokeAllSessions(career.Id)
+		}
+		return career, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return models.User{}, err
+	}
+
+	career = newOIDCCareer(claims, email, link)
+	if _, err := users.InsertOne(context.Background(), career); err != nil {
+		return models.User{}, err
+	}
+	return career, nil
+}
+
+// linkIdentityUpdate links a provider identity to a career registered with the same
+// email. Whoever registered an unverified career never proved owning the address, so
+// its password and sessions are dropped and the provider login takes it over. ADMIN
+// accounts are never linked, they only sign in with their password and MFA.
+func linkIdentityUpdate(career models.User, link models.ExternalIdentity) (bson.M, bool, error) {
+	if NormalizeRole(career.Role) == constants.ADMIN {
+		return nil, false, ErrOIDCLinkRefused
+	}
+
+	update := bson.M{"$push": bson.M{"identities": link}}
+	if career.IsVerified {
+		return update, false, nil
+	}
+	update["$set"] = bson.M{"isVerified": true, "verifiedAt": link.LinkedAt}
+	update["$unset"] = bson.M{"password": ""}
+	return update, true, nil
+}
+
+// newOIDCCareer registers a verified career for a provider identity without a match
+func newOIDCCareer(claims *oidcClaims, email string, link models.ExternalIdentity) models.User {
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName = claims.Name
+	}
+	return models.User{
+		Id:            primitive.NewObjectID(),
+		FirstName:     firstName,
+		LastName:      lastName,
+		CareerEmail:   email,
+		CareerPicture: claims.Picture,
+		CreateAt:      link.LinkedAt,
+		Languages:     []string{},
+		Role:          constants.CAREER,
+		Profile:       models.Profile{UserCV: []string{}, Skills: []string{}},
+		IsVerified:    true,
+		VerifiedAt:    link.LinkedAt,
+		Identities:    []models.ExternalIdentity{link},
+	}
+}
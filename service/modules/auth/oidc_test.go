@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	testClientID    = "hireforwork"
+	testRedirectURL = "https://app.example/careers/auth/oidc/callback"
+	testKid         = "issuer-key"
+	testCode        = "authorization-code"
+)
+
+// fakeIssuer is a minimal OpenID provider: it serves discovery and its JWKS, and
+// exchanges testCode for idToken once the PKCE verifier matches the challenge
+type fakeIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+	// issuer advertised by discovery, the server URL unless a test overrides it
+	issuer string
+
+	mu        sync.Mutex
+	challenge string
+	idToken   string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                f.issuer,
+			AuthorizationEndpoint: f.URL + "/authorize",
+			TokenEndpoint:         f.URL + "/token",
+			JwksURI:               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{
+			Kty: "RSA",
+			Kid: testKid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != testCode || base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(oidcTokenResponse{Error: "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(oidcTokenResponse{IDToken: f.idToken})
+	})
+
+	f.Server = httptest.NewServer(mux)
+	f.issuer = f.URL
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeIssuer) strategy() *OIDCLoginStrategy {
+	return &OIDCLoginStrategy{
+		issuer:      f.URL,
+		clientID:    testClientID,
+		redirectURL: testRedirectURL,
+		scopes:      []string{"openid", "email", "profile"},
+		HTTPClient:  f.Client(),
+	}
+}
+
+// sign issues an ID token with the issuer key, or with key when it is not nil
+func (f *fakeIssuer) sign(t *testing.T, claims *oidcClaims, key *rsa.PrivateKey) string {
+	t.Helper()
+	if key == nil {
+		key = f.key
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func (f *fakeIssuer) claims(nonce string) *oidcClaims {
+	now := time.Now()
+	return &oidcClaims{
+		Nonce:         nonce,
+		Email:         "career@example.com",
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    f.URL,
+			Subject:   "provider-subject",
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  func(f *fakeIssuer) string
+		wantErr bool
+	}{
+		{"matching issuer", func(f *fakeIssuer) string { return f.URL }, false},
+		{"trailing slash", func(f *fakeIssuer) string { return f.URL + "/" }, false},
+		{"mismatched issuer", func(f *fakeIssuer) string { return "https://evil.example" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			f.issuer = tt.issuer(f)
+
+			discovery, err := f.strategy().getDiscovery()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getDiscovery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && discovery.TokenEndpoint != f.URL+"/token" {
+				t.Errorf("TokenEndpoint = %q", discovery.TokenEndpoint)
+			}
+		})
+	}
+}
+
+func TestOIDCAuthorizationURL(t *testing.T) {
+	f := newFakeIssuer(t)
+	o := f.strategy()
+	discovery, err := o.getDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := o.authorizationURL(discovery, "the-state", "the-verifier", "the-nonce")
+	if !strings.HasPrefix(raw, f.URL+"/authorize?") {
+		t.Fatalf("authorization URL %q does not use the discovered endpoint", raw)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte("the-verifier"))
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+		"code_challenge_method": "S256",
+	}
+	query := parsed.Query()
+	for param, value := range want {
+		if got := query.Get(param); got != value {
+			t.Errorf("%s = %q, want %q", param, got, value)
+		}
+	}
+	if query.Has("code_verifier") {
+		t.Error("the PKCE verifier must never leave the server")
+	}
+}
+
+func TestOIDCStateCookie(t *testing.T) {
+	o := &OIDCLoginStrategy{redirectURL: testRedirectURL}
+	cookie := o.StateCookie("the-state")
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("state cookie flags = %+v", cookie)
+	}
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+		want   bool
+	}{
+		{"same browser", cookie, "the-state", true},
+		{"other state", cookie, "another-state", false},
+		{"no cookie", nil, "the-state", false},
+		{"empty state", o.StateCookie(""), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/careers/auth/oidc/callback", nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			if got := o.StateMatchesCookie(r, tt.state); got != tt.want {
+				t.Errorf("StateMatchesCookie() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCCodeExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		verifier string
+		wantErr  bool
+	}{
+		{"matching verifier", testCode, "the-verifier", false},
+		{"wrong verifier", testCode, "another-verifier", true},
+		{"wrong code", "another-code", "the-verifier", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			o := f.strategy()
+			discovery, err := o.getDiscovery()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The provider keeps the challenge sent with the authorization request
+			parsed, _ := url.Parse(o.authorizationURL(discovery, "the-state", "the-verifier", "the-nonce"))
+			f.challenge = parsed.Query().Get("code_challenge")
+			f.idToken = f.sign(t, f.claims("the-nonce"), nil)
+
+			idToken, err := o.exchangeCode(tt.code, tt.verifier)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("exchangeCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && idToken != f.idToken {
+				t.Error("exchangeCode() did not return the issued ID token")
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(c *oidcClaims)
+		key     *rsa.PrivateKey
+		wantErr bool
+	}{
+		{"valid", func(c *oidcClaims) {}, nil, false},
+		{"audience among others", func(c *oidcClaims) { c.Audience = jwt.ClaimStrings{"other", testClientID} }, nil, false},
+		{"wrong nonce", func(c *oidcClaims) { c.Nonce = "replayed" }, nil, true},
+		{"wrong issuer", func(c *oidcClaims) { c.Issuer = "https://evil.example" }, nil, true},
+		{"wrong audience", func(c *oidcClaims) { c.Audience = jwt.ClaimStrings{"other-client"} }, nil, true},
+		{"expired", func(c *oidcClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }, nil, true},
+		{"no expiry", func(c *oidcClaims) { c.ExpiresAt = nil }, nil, true},
+		{"no subject", func(c *oidcClaims) { c.Subject = "" }, nil, true},
+		{"signed by another key", func(c *oidcClaims) {}, otherKey, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			claims := f.claims("the-nonce")
+			tt.tamper(claims)
+
+			got, err := f.strategy().verifyIDToken(f.sign(t, claims, tt.key), "the-nonce")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("verifyIDToken() error = %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyIDToken() error = %v", err)
+			}
+			if got.Subject != "provider-subject" || !got.emailVerified() {
+				t.Errorf("verifyIDToken() claims = %+v", got)
+			}
+		})
+	}
+}
+
+func TestLinkIdentityUpdate(t *testing.T) {
+	link := models.ExternalIdentity{Issuer: "https://issuer.example", Subject: "provider-subject", LinkedAt: primitive.NewDateTimeFromTime(time.Now())}
+
+	tests := []struct {
+		name       string
+		career     models.User
+		wantErr    error
+		wantRevoke bool
+	}{
+		{"verified career", models.User{Role: constants.CAREER, IsVerified: true}, nil, false},
+		{"career without role", models.User{IsVerified: true}, nil, false},
+		{"unverified career", models.User{Role: constants.CAREER, IsVerified: false}, nil, true},
+		{"admin", models.User{Role: constants.ADMIN, IsVerified: true}, ErrOIDCLinkRefused, false},
+		{"lowercase admin", models.User{Role: "admin", IsVerified: false}, ErrOIDCLinkRefused, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, revoke, err := linkIdentityUpdate(tt.career, link)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("linkIdentityUpdate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if revoke != tt.wantRevoke {
+				t.Errorf("revoke = %v, want %v", revoke, tt.wantRevoke)
+			}
+			if push, _ := update["$push"].(bson.M); push["identities"] != link {
+				t.Errorf("$push = %v, want the identity", update["$push"])
+			}
+
+			unset, _ := update["$unset"].(bson.M)
+			_, dropsPassword := unset["password"]
+			if dropsPassword != !tt.career.IsVerified {
+				t.Errorf("password dropped = %v for verified = %v", dropsPassword, tt.career.IsVerified)
+			}
+		})
+	}
+}
+
+func TestNewOIDCCareer(t *testing.T) {
+	link := models.ExternalIdentity{Issuer: "https://issuer.example", Subject: "provider-subject", LinkedAt: primitive.NewDateTimeFromTime(time.Now())}
+
+	tests := []struct {
+		name      string
+		claims    oidcClaims
+		wantFirst string
+		wantLast  string
+	}{
+		{"given and family name", oidcClaims{GivenName: "An", FamilyName: "Nguyen", Name: "Nguyen An"}, "An", "Nguyen"},
+		{"only full name", oidcClaims{Name: "Nguyen An"}, "Nguyen An", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			career := newOIDCCareer(&tt.claims, "career@example.com", link)
+			if career.FirstName != tt.wantFirst || career.LastName != tt.wantLast {
+				t.Errorf("name = %q %q, want %q %q", career.FirstName, career.LastName, tt.wantFirst, tt.wantLast)
+			}
+			if career.Role != constants.CAREER || !career.IsVerified || career.Password != "" {
+				t.Errorf("career = %+v, want a verified CAREER without password", career)
+			}
+			if len(career.Identities) != 1 || career.Identities[0] != link {
+				t.Errorf("identities = %v, want the provider identity", career.Identities)
+			}
+		})
+	}
+}
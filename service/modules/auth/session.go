@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrSessionNotFound = errors.New("Không tìm thấy phiên đăng nhập")
+
+// sessionTouchCache limits last-used updates to one per session and window
+var sessionTouchCache = cache.New(time.Minute, 5*time.Minute)
+
+// Session is an active login of an account, identified by its refresh token family
+type Session struct {
+	Id         string             `json:"_id"`
+	Device     string             `json:"device"`
+	UserAgent  string             `json:"userAgent"`
+	IP         string             `json:"ip"`
+	LoginAt    primitive.DateTime `json:"loginAt"`
+	LastUsedAt primitive.DateTime `json:"lastUsedAt"`
+	ExpireAt   primitive.DateTime `json:"expireAt"`
+	Current    bool               `json:"current"`
+	// the admin who opened this session for support, if any
+	ImpersonatedBy string `json:"impersonatedBy,omitempty"`
+}
+
+// ListSessions returns the active sessions of an account, most recently used first.
+// Every session has exactly one refresh token that was not rotated yet.
+func (a *AuthService) ListSessions(userID string, currentSessionID string) ([]Session, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+
+	cursor, err := a.refreshTokenCollection.Find(
+		context.Background(),
+		bson.M{
+			"userID":    userObjID,
+			"isUsed":    false,
+			"isRevoked": false,
+			"expireAt":  bson.M{"$gt": time.Now()},
+		},
+		options.Find().SetSort(bson.D{{"lastUsedAt", -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var tokens []models.RefreshToken
+	if err := cursor.All(context.Background(), &tokens); err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	for _, token := range tokens {
+		sessions = append(sessions, Session{
+			Id:             token.FamilyID.Hex(),
+			Device:         utils.DescribeUserAgent(token.UserAgent),
+			UserAgent:      token.UserAgent,
+			IP:             token.IP,
+			LoginAt:        token.LoginAt,
+			LastUsedAt:     token.LastUsedAt,
+			ExpireAt:       token.ExpireAt,
+			Current:        token.FamilyID.Hex() == currentSessionID,
+			ImpersonatedBy: token.ImpersonatedBy,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one session of the account out, e.g. a lost laptop
+func (a *AuthService) RevokeSession(userID string, sessionID string) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	familyID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	count, err := a.refreshTokenCollection.CountDocuments(context.Background(), bson.M{
+		"userID":    userObjID,
+		"familyID":  familyID,
+		"isRevoked": false,
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSessionNotFound
+	}
+
+	a.revokeFamily(familyID)
+	return nil
+}
+
+// RevokeOtherSessions signs the account out everywhere but the current session
+func (a *AuthService) RevokeOtherSessions(userID string, currentSessionID string) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	currentFamilyID, err := primitive.ObjectIDFromHex(currentSessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	familyIDs, err := a.refreshTokenCollection.Distinct(context.Background(), "familyID", bson.M{
+		"userID":    userObjID,
+		"isRevoked": false,
+		"familyID":  bson.M{"$ne": currentFamilyID},
+	})
+	if err != nil {
+		return err
+	}
+	for _, familyID := range familyIDs {
+		if id, ok := familyID.(primitive.ObjectID); ok {
+			a.revokeFamily(id)
+		}
+	}
+	return nil
+}
+
+// TouchSession records that the session's access token was just used
+func (a *AuthService) TouchSession(sessionID string) {
+	if _, found := sessionTouchCache.Get(sessionID); found {
+		return
+	}
+	familyID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return
+	}
+	sessionTouchCache.Set(sessionID, true, cache.DefaultExpiration)
+
+	_, err = a.refreshTokenCollection.UpdateOne(
+		context.Background(),
+		bson.M{"familyID": familyID, "isUsed": false, "isRevoked": false},
+		bson.M{"$set": bson.M{"lastUsedAt": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		log.Printf("Error updating session %s: %v", sessionID, err)
+	}
+}
@@ -1,7 +1,10 @@
 package auth
 
 import (
-	"github.com/dgrijalva/jwt-go"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -9,23 +12,105 @@ type Credentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Role     string `json:"role"`
+	// authorization code and state returned by an OpenID Connect provider
+	Code  string `json:"code,omitempty"`
+	State string `json:"state,omitempty"`
+	// set by the handler, used to throttle failed logins per client and to
+	// describe the session
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// ClientInfo describes the device a session is opened or refreshed from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+func (c Credentials) Client() ClientInfo {
+	return ClientInfo{IP: c.IP, UserAgent: c.UserAgent}
 }
 
 type AuthService struct {
-	userCollection    *mongo.Collection
-	companyCollection *mongo.Collection
-	JwtSecret         []byte
+	userCollection         *mongo.Collection
+	companyCollection      *mongo.Collection
+	refreshTokenCollection *mongo.Collection
+	loginAttemptCollection *mongo.Collection
+	memberCollection       *mongo.Collection
+	apiKeyCollection       *mongo.Collection
+	oidcStateCollection    *mongo.Collection
+	JwtSecret              []byte
+	Keys                   *KeySet
+	AccessTokenTTL         time.Duration
+	RefreshTokenTTL        time.Duration
+	RequireAdminMFA        bool
 }
 
 type Claims struct {
-	Username string `json:"userName"`
-	Role     string `json:"role"`
-	Id       string `json:"userId"`
-	jwt.StandardClaims
+	Username  string `json:"userName"`
+	Role      string `json:"role"`
+	Id        string `json:"userId"`
+	SessionID string `json:"sid"`
+	// set for company tokens, the subject is then the member acting for the company
+	CompanyID  string `json:"companyId,omitempty"`
+	MemberRole string `json:"memberRole,omitempty"`
+	// set on support sessions, the admin acting as the subject
+	ImpersonatedBy string `json:"imp,omitempty"`
+	// set when the request is authenticated with a company API key instead of a JWT
+	APIKeyID string   `json:"-"`
+	Scopes   []string `json:"-"`
+	jwt.RegisteredClaims
+}
+
+// GetCompanyID returns the company the token acts for, members share their company's ID
+func (c *Claims) GetCompanyID() string {
+	if c.CompanyID != "" {
+		return c.CompanyID
+	}
+	return c.Subject
 }
 
+// HasScope reports whether the claims come from an API key granted one of the scopes
+func (c *Claims) HasScope(allowed []string) bool {
+	for _, scope := range c.Scopes {
+		for _, a := range allowed {
+			if scope == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Principal is the authenticated account a token pair is issued for
+type Principal struct {
+	Id             primitive.ObjectID
+	Username       string
+	Role           string
+	CompanyID      string
+	MemberRole     string
+	ImpersonatedBy string
+}
+
+// LoginResponse either carries the token pair or, when a second factor is needed,
+// only an MFA token to present to the verify (or enroll) endpoint
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token                 string `json:"token,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+	ExpiresIn             int64  `json:"expiresIn,omitempty"`
+	MFARequired           bool   `json:"mfaRequired,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfaEnrollmentRequired,omitempty"`
+	MFAToken              string `json:"mfaToken,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// MFARequest carries the MFA token of a pending login (if any) and a TOTP or recovery code
+type MFARequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code"`
 }
 
 type LoginConfig struct {
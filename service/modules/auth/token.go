@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("Phiên đăng nhập không hợp lệ hoặc đã hết hạn")
+	ErrRefreshTokenReused  = errors.New("Phiên đăng nhập đã bị thu hồi, vui lòng đăng nhập lại")
+)
+
+var indexOnce sync.Once
+
+// sessionCache keeps the revocation status of a session for a short time so that
+// GlobalMiddleware does not hit Mongo on every request
+var sessionCache = cache.New(30*time.Second, time.Minute)
+
+func (a *AuthService) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := a.refreshTokenCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"tokenHash", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"familyID", 1}}},
+		{Keys: bson.D{{"userID", 1}, {"isUsed", 1}}},
+		{Keys: bson.D{{"expireAt", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Error creating refresh token indexes: %v", err)
+	}
+
+	_, err = a.loginAttemptCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"expireAt", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Error creating login attempt indexes: %v", err)
+	}
+
+	_, err = a.userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"identities.issuer", 1}, {"identities.subject", 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		log.Printf("Error creating external identity indexes: %v", err)
+	}
+
+	_, err = a.oidcStateCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"expireAt", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Error creating OIDC state indexes: %v", err)
+	}
+
+	_, err = a.apiKeyCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"keyHash", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"companyID", 1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating API key indexes: %v", err)
+	}
+}
+
+// accountType tells which login endpoint a role belongs to
+func accountType(role string) string {
+	if role == constants.COMPANY {
+		return constants.COMPANY
+	}
+	return constants.CAREER
+}
+
+// IssueTokens starts a new session: a short-lived access token plus the first
+// refresh token of a new family
+func (a *AuthService) IssueTokens(principal Principal, client ClientInfo) (LoginResponse, error) {
+	return a.issueTokens(principal, primitive.NewObjectID(), time.Now(), client)
+}
+
+func (a *AuthService) issueTokens(principal Principal, familyID primitive.ObjectID, loginAt time.Time, client ClientInfo) (LoginResponse, error) {
+	refreshToken, err := utils.RandomToken(32)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	now := time.Now()
+	record := models.RefreshToken{
+		Id:          primitive.NewObjectID(),
+		FamilyID:    familyID,
+		UserID:      principal.Id,
+		UserName:    principal.Username,
+		Role:        principal.Role,
+		AccountType: accountType(principal.Role),
+		CompanyID:   principal.CompanyID,
+		MemberRole:  principal.MemberRole,
+		TokenHash:   utils.HashToken(refreshToken),
+		LoginAt:     primitive.NewDateTimeFromTime(loginAt),
+		IP:          client.IP,
+		UserAgent:   client.UserAgent,
+		LastUsedAt:  primitive.NewDateTimeFromTime(now),
+		CreateAt:    primitive.NewDateTimeFromTime(now),
+		ExpireAt:    primitive.NewDateTimeFromTime(now.Add(a.RefreshTokenTTL)),
+	}
+	if _, err := a.refreshTokenCollection.InsertOne(context.Background(), record); err != nil {
+		return LoginResponse{}, err
+	}
+
+	token, err := a.GenerateToken(principal, familyID.Hex())
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(a.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// Refresh rotates the refresh token. Presenting a token that was already rotated
+// means it leaked, so the whole family is revoked.
+func (a *AuthService) Refresh(refreshToken string, expectedAccountType string, client ClientInfo) (LoginResponse, error) {
+	var stored models.RefreshToken
+	err := a.refreshTokenCollection.FindOne(context.Background(), bson.M{"tokenHash": utils.HashToken(refreshToken)}).Decode(&stored)
+	if err != nil {
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	if stored.IsRevoked || stored.AccountType != expectedAccountType || stored.ExpireAt.Time().Before(time.Now()) {
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+	if stored.IsUsed {
+		a.revokeFamily(stored.FamilyID)
+		return LoginResponse{}, ErrRefreshTokenReused
+	}
+
+	// The account is read again, a deleted, suspended or removed one gets no new token
+	// and roles changed since the login apply from now on
+	principal, _, _, err := a.loadAccount(stored.AccountType, stored.UserID)
+	if err != nil {
+		a.revokeFamily(stored.FamilyID)
+		return LoginResponse{}, ErrInvalidRefreshToken
+	}
+
+	// Mark as used atomically so two concurrent refreshes cannot both succeed
+	result, err := a.refreshTokenCollection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": stored.Id, "isUsed": false, "isRevoked": false},
+		bson.M{"$set": bson.M{"isUsed": true}},
+	)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if result.ModifiedCount == 0 {
+		a.revokeFamily(stored.FamilyID)
+		return LoginResponse{}, ErrRefreshTokenReused
+	}
+
+	// Sessions created before login times were recorded start at their first refresh
+	loginAt := stored.LoginAt.Time()
+	if stored.LoginAt == 0 {
+		loginAt = stored.CreateAt.Time()
+	}
+
+	return a.issueTokens(principal, stored.FamilyID, loginAt, client)
+}
+
+// Logout revokes the session of the refresh token and/or the current access token
+func (a *AuthService) Logout(refreshToken string, sessionID string) error {
+	if refreshToken != "" {
+		var stored models.RefreshToken
+		err := a.refreshTokenCollection.FindOne(context.Background(), bson.M{"tokenHash": utils.HashToken(refreshToken)}).Decode(&stored)
+		if err == nil {
+			a.revokeFamily(stored.FamilyID)
+		}
+	}
+
+	if sessionID != "" {
+		familyID, err := primitive.ObjectIDFromHex(sessionID)
+		if err == nil {
+			a.revokeFamily(familyID)
+		}
+	}
+
+	if refreshToken == "" && sessionID == "" {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+func (a *AuthService) revokeFamily(familyID primitive.ObjectID) {
+	_, err := a.refreshTokenCollection.UpdateMany(
+		context.Background(),
+		bson.M{"familyID": familyID, "isRevoked": false},
+		bson.M{"$set": bson.M{"isRevoked": true}},
+	)
+	if err != nil {
+		log.Printf("Error revoking session %s: %v", familyID.Hex(), err)
+		return
+	}
+	sessionCache.Set(familyID.Hex(), true, cache.DefaultExpiration)
+}
+
+// IsSessionRevoked reports whether the access token's session has been logged out,
+// rotated into a revoked family or expired. Tokens without a session are rejected.
+func (a *AuthService) IsSessionRevoked(claims *Claims) bool {
+	if claims.SessionID == "" {
+		return true
+	}
+	if revoked, found := sessionCache.Get(claims.SessionID); found {
+		return revoked.(bool)
+	}
+
+	familyID, err := primitive.ObjectIDFromHex(claims.SessionID)
+	if err != nil {
+		return true
+	}
+
+	count, err := a.refreshTokenCollection.CountDocuments(context.Background(), bson.M{
+		"familyID":  familyID,
+		"isRevoked": false,
+		"expireAt":  bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		log.Printf("Error checking session %s: %v", claims.SessionID, err)
+		return true
+	}
+
+	revoked := count == 0
+	sessionCache.Set(claims.SessionID, revoked, cache.DefaultExpiration)
+	return revoked
+}
+
+// RevokeAllSessions signs the account out everywhere, used after a password change
+func (a *AuthService) RevokeAllSessions(userID primitive.ObjectID) {
+	a.revokeSessionsMatching(bson.M{"userID": userID, "isRevoked": false}, userID.Hex())
+}
+
+// RevokeCompanySessions signs the company account and all of its members out
+func (a *AuthService) RevokeCompanySessions(companyID string) {
+	a.revokeSessionsMatching(bson.M{"companyID": companyID, "isRevoked": false}, companyID)
+}
+
+func (a *AuthService) revokeSessionsMatching(filter bson.M, owner string) {
+	familyIDs, err := a.refreshTokenCollection.Distinct(context.Background(), "familyID", filter)
+	if err != nil {
+		log.Printf("Error listing sessions of %s: %v", owner, err)
+		return
+	}
+	for _, familyID := range familyIDs {
+		if id, ok := familyID.(primitive.ObjectID); ok {
+			a.revokeFamily(id)
+		}
+	}
+}
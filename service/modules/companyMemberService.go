@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireforwork-server/config"
+	"hireforwork-server/constants"
+	"hireforwork-server/db"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/models"
+	"hireforwork-server/service/modules/auth"
+	"hireforwork-server/utils"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	memberInvitePurpose = "member-invite"
+	memberInviteTTL     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidMemberRole  = errors.New("Vai trò thành viên không hợp lệ")
+	ErrMemberExists       = errors.New("Email này đã thuộc một tài khoản công ty")
+	ErrMemberNotFound     = errors.New("Không tìm thấy thành viên")
+	ErrInvalidInvitation  = errors.New("Lời mời không hợp lệ hoặc đã hết hạn")
+	ErrCannotRemoveMember = errors.New("Không thể tự xóa chính mình khỏi công ty")
+)
+
+var memberIndexOnce sync.Once
+
+type CompanyMemberService struct {
+	memberCollection  *mongo.Collection
+	companyCollection *mongo.Collection
+	authService       *auth.AuthService
+	secret            []byte
+	baseURL           string
+}
+
+func NewCompanyMemberService(dbInstance *db.DB) *CompanyMemberService {
+	collections := dbInstance.GetCollections([]string{"CompanyMember", "Company"})
+	cfg := config.GetInstance()
+
+	memberService := &CompanyMemberService{
+		memberCollection:  collections[0],
+		companyCollection: collections[1],
+		authService:       auth.NewAuthService(dbInstance),
+		secret:            []byte(cfg.SecretKey),
+		baseURL:           cfg.AppBaseURL,
+	}
+	memberIndexOnce.Do(memberService.ensureIndexes)
+
+	return memberService
+}
+
+func (m *CompanyMemberService) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.memberCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"email", 1}, {"status", 1}}},
+		{Keys: bson.D{{"companyID", 1}}},
+		// Two invitations racing past emailTaken must not both be stored
+		{
+			Keys: bson.D{{"email", 1}},
+			Options: options.Index().SetName("email_live_unique").SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": bson.M{"$in": bson.A{constants.MEMBER_INVITED, constants.MEMBER_ACTIVE}},
+			}),
+		},
+	})
+	if err != nil {
+		log.Printf("Error creating company member indexes: %v", err)
+	}
+}
+
+func isMemberRole(role string) bool {
+	return role == constants.MEMBER_OWNER || role == constants.MEMBER_RECRUITER || role == constants.MEMBER_VIEWER
+}
+
+// emailTaken reports whether the email already signs in to a company, as a company
+// contact or as an invited or active member
+func (m *CompanyMemberService) emailTaken(email string) (bool, error) {
+	return companyEmailTaken(m.memberCollection, m.companyCollection, email, primitive.NilObjectID)
+}
+
+// companyEmailTaken is emailTaken for services holding the collections, except skips
+// the company changing its own contact email
+func companyEmailTaken(memberCollection, companyCollection *mongo.Collection, email string, except primitive.ObjectID) (bool, error) {
+	count, err := memberCollection.CountDocuments(context.Background(), bson.M{
+		"email":  strings.ToLower(email),
+		"status": bson.M{"$ne": constants.MEMBER_REMOVED},
+	})
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	count, err = companyCollection.CountDocuments(context.Background(), bson.M{
+		"_id":                  bson.M{"$ne": except},
+		"contact.companyEmail": bson.M{"$regex": "^" + regexp.QuoteMeta(email) + "$", "$options": "i"},
+		"isDeleted":            false,
+	})
+	return count > 0, err
+}
+
+// InviteMember creates an invited member and emails them a link to set their password
+func (m *CompanyMemberService) InviteMember(companyID string, invitedBy string, invite interfaces.IMemberInvite) (models.CompanyMember, error) {
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return models.CompanyMember{}, ErrMemberNotFound
+	}
+	inviterObjID, _ := primitive.ObjectIDFromHex(invitedBy)
+
+	invite.MemberRole = strings.ToUpper(strings.TrimSpace(invite.MemberRole))
+	if !isMemberRole(invite.MemberRole) {
+		return models.CompanyMember{}, ErrInvalidMemberRole
+	}
+
+	email := strings.ToLower(strings.TrimSpace(invite.Email))
+	if email == "" {
+		return models.CompanyMember{}, errors.New("Email không được để trống")
+	}
+	taken, err := m.emailTaken(email)
+	if err != nil {
+		return models.CompanyMember{}, err
+	}
+	if taken {
+		return models.CompanyMember{}, ErrMemberExists
+	}
+
+	var company models.Company
+	if err := m.companyCollection.FindOne(context.Background(), bson.M{"_id": companyObjID, "isDeleted": false}).Decode(&company); err != nil {
+		return models.CompanyMember{}, ErrMemberNotFound
+	}
+
+	member := models.CompanyMember{
+		Id:         primitive.NewObjectID(),
+		CompanyID:  companyObjID,
+		Email:      email,
+		Name:       invite.Name,
+		MemberRole: invite.MemberRole,
+		Status:     constants.MEMBER_INVITED,
+		InvitedBy:  inviterObjID,
+		CreateAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if _, err := m.memberCollection.InsertOne(context.Background(), member); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.CompanyMember{}, ErrMemberExists
+		}
+		return models.CompanyMember{}, err
+	}
+
+	token := utils.SignToken(m.secret, memberInvitePurpose, member.Id.Hex(), memberInviteTTL)
+	link := fmt.Sprintf("%s/companies/members/accept?token=%s", m.baseURL, token)
+	subject := fmt.Sprintf("Lời mời tham gia %s", company.CompanyName)
+	body := fmt.Sprintf(`Bạn được mời tham gia tài khoản tuyển dụng của %s. Bấm vào <a href="%s">liên kết này</a> để tạo mật khẩu.`, company.CompanyName, link)
+	if err := SendEmail(email, subject, body); err != nil {
+		log.Printf("Error sending invitation to %s: %v", email, err)
+	}
+
+	return member, nil
+}
+
+// AcceptInvitation activates an invited member with the password they chose
+func (m *CompanyMemberService) AcceptInvitation(accept interfaces.IMemberAccept) (models.CompanyMember, error) {
+	subject, err := utils.VerifySignedToken(m.secret, accept.Token, memberInvitePurpose)
+	if err != nil {
+		return models.CompanyMember{}, ErrInvalidInvitation
+	}
+	memberID, err := primitive.ObjectIDFromHex(subject)
+	if err != nil {
+		return models.CompanyMember{}, ErrInvalidInvitation
+	}
+	if accept.Password == "" {
+		return models.CompanyMember{}, ErrEmptyPassword
+	}
+
+	hashedPassword, err := utils.HashPassword(accept.Password)
+	if err != nil {
+		return models.CompanyMember{}, err
+	}
+
+	set := bson.M{
+		"password": hashedPassword,
+		"status":   constants.MEMBER_ACTIVE,
+		"joinedAt": primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if accept.Name != "" {
+		set["name"] = accept.Name
+	}
+
+	// Only an invitation still pending can be accepted, so the link is single-use
+	var member models.CompanyMember
+	err = m.memberCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": memberID, "status": constants.MEMBER_INVITED},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&member)
+	if err != nil {
+		return models.CompanyMember{}, ErrInvalidInvitation
+	}
+	return member, nil
+}
+
+func (m *CompanyMemberService) ListMembers(companyID string) ([]models.CompanyMember, error) {
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return nil, ErrMemberNotFound
+	}
+
+	cursor, err := m.memberCollection.Find(
+		context.Background(),
+		bson.M{"companyID": companyObjID, "status": bson.M{"$ne": constants.MEMBER_REMOVED}},
+		options.Find().SetSort(bson.D{{"createAt", 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	members := []models.CompanyMember{}
+	if err := cursor.All(context.Background(), &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// RemoveMember revokes a member's access and signs them out everywhere
+func (m *CompanyMemberService) RemoveMember(companyID string, memberID string, removedBy string) error {
+	if memberID == removedBy {
+		return ErrCannotRemoveMember
+	}
+	companyObjID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return ErrMemberNotFound
+	}
+	memberObjID, err := primitive.ObjectIDFromHex(memberID)
+	if err != nil {
+		return ErrMemberNotFound
+	}
+
+	result, err := m.memberCollection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": memberObjID, "companyID": companyObjID, "status": bson.M{"$ne": constants.MEMBER_REMOVED}},
+		bson.M{
+			"$set":   bson.M{"status": constants.MEMBER_REMOVED},
+			"$unset": bson.M{"password": "", "mfa": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrMemberNotFound
+	}
+
+	m.authService.RevokeAllSessions(memberObjID)
+	return nil
+}
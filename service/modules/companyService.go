@@ -4,14 +4,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/interfaces"
 	"hireforwork-server/models"
+	"hireforwork-server/service/modules/geo"
 	"hireforwork-server/utils"
 	"log"
 	"math"
-	"math/rand"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,17 +23,74 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var companyLocationOnce sync.Once
+
 type CompanyService struct {
 	companyCollection, jobCollection, careerApplyJob *mongo.Collection
+	memberCollection                                 *mongo.Collection
+	verification                                     *VerificationService
+	passwordReset                                    *PasswordResetService
+	geocoder                                         geo.Geocoder
 }
 
 func NewCompanyService(dbInstance *db.DB) *CompanyService {
-	c := dbInstance.GetCollections([]string{"Company", "Job", "CareerApplyJob"})
-	return &CompanyService{
+	c := dbInstance.GetCollections([]string{"Company", "Job", "CareerApplyJob", "CompanyMember"})
+	companyService := &CompanyService{
 		companyCollection: c[0],
 		jobCollection:     c[1],
 		careerApplyJob:    c[2],
+		memberCollection:  c[3],
+		verification:      NewVerificationService(dbInstance),
+		passwordReset:     NewPasswordResetService(dbInstance),
+		geocoder:          geo.NewGazetteer(),
+	}
+	// Geocoding every company must not hold up a cold start
+	companyLocationOnce.Do(func() {
+		go companyService.locateCompanies()
+	})
+	return companyService
+}
+
+// locateCompanies indexes company locations and geocodes the address of companies saved
+// before they carried one
+func (c *CompanyService) locateCompanies() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := c.companyCollection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"location", "2dsphere"}}})
+	if err != nil {
+		log.Printf("Error creating company location index: %v", err)
+	}
+
+	filter := bson.M{"location": bson.M{"$exists": false}, "contact.companyAddress": bson.M{"$nin": bson.A{nil, ""}}}
+	opts := options.Find().SetProjection(bson.M{"contact.companyAddress": 1})
+	cursor, err := c.companyCollection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Error reading companies to locate: %v", err)
+		return
 	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var company models.Company
+		if err := cursor.Decode(&company); err != nil {
+			continue
+		}
+		location, ok := c.geocoder.Geocode(company.Contact.CompanyAddress)
+		if !ok {
+			continue
+		}
+		if _, err := c.companyCollection.UpdateByID(ctx, company.Id, bson.M{"$set": bson.M{"location": location}}); err != nil {
+			log.Printf("Error locating company %s: %v", company.Id.Hex(), err)
+		}
+	}
+}
+
+// companyLocation geocodes the address of a company, nil when the address is unknown
+func (c *CompanyService) companyLocation(company models.Company) *models.GeoPoint {
+	if location, ok := c.geocoder.Geocode(company.Contact.CompanyAddress); ok {
+		return &location
+	}
+	return nil
 }
 
 // Lấy danh sách company với phân trang
@@ -47,7 +107,10 @@ func (c *CompanyService) GetCompanies(page int, pageSize int, filter interfaces.
 
 	skip := (page - 1) * pageSize
 
-	bsonFilter := bson.D{{"isDeleted", false}}
+	bsonFilter, err := accountStatusFilter(filter.Status)
+	if err != nil {
+		return models.PaginateDocs[models.Company]{}, err
+	}
 
 	if filter.CompanyName != "" {
 		bsonFilter = append(bsonFilter, bson.E{"companyName", bson.D{{"$regex", filter.CompanyName}, {"$options", "i"}}})
@@ -57,6 +120,10 @@ func (c *CompanyService) GetCompanies(page int, pageSize int, filter interfaces.
 		bsonFilter = append(bsonFilter, bson.E{"contact.companyEmail", bson.D{{"$regex", filter.CompanyEmail}, {"$options", "i"}}})
 	}
 
+	if filter.IsVerified != nil {
+		bsonFilter = append(bsonFilter, bson.E{"isVerified", *filter.IsVerified})
+	}
+
 	if filter.StartDate != nil || filter.EndDate != nil {
 		dateFilter := bson.D{}
 
@@ -152,12 +219,35 @@ func (c *CompanyService) GetRandomCompany() (models.Company, error) {
 }
 
 func (c *CompanyService) CreateCompany(company models.Company) (models.Company, error) {
+	// Members sign in with the company login too, an email can only belong to one
+	taken, err := companyEmailTaken(c.memberCollection, c.companyCollection, company.Contact.CompanyEmail, primitive.NilObjectID)
+	if err != nil {
+		return models.Company{}, err
+	}
+	if taken {
+		return models.Company{}, ErrEmailTaken
+	}
+
+	hashedPassword, err := utils.HashPassword(company.Password)
+	if err != nil {
+		return models.Company{}, err
+	}
+	company.Password = hashedPassword
+	company.Id = primitive.NewObjectID()
+	company.IsVerified = false
+	company.Location = c.companyLocation(company)
 
 	result, err := c.companyCollection.InsertOne(context.Background(), company)
 	if err != nil {
 		return models.Company{}, err
 	}
 	company.Id = result.InsertedID.(primitive.ObjectID)
+
+	// The account exists even if the mail fails, the company can ask for a new link
+	if err := c.verification.SendVerificationEmail(constants.COMPANY, company.Id, company.Contact.CompanyEmail); err != nil {
+		log.Printf("Error sending verification email to %s: %v", company.Contact.CompanyEmail, err)
+	}
+	company.Password = ""
 	return company, nil
 }
 
@@ -185,6 +275,16 @@ func (c *CompanyService) DeleteCompanyByID(companyID string) http.Response {
 	}
 }
 
+// SuspendCompany blocks the company and its members from signing in until it is restored
+func (c *CompanyService) SuspendCompany(companyID string, reason string) error {
+	return suspendAccount(c.companyCollection, companyID, reason)
+}
+
+// RestoreCompany lifts a suspension or undoes a deletion
+func (c *CompanyService) RestoreCompany(companyID string) error {
+	return restoreAccount(c.companyCollection, companyID)
+}
+
 func (c *CompanyService) UpdateCompanyByID(companyID string, updatedCompany models.Company) (models.Company, error) {
 	_id, err := primitive.ObjectIDFromHex(companyID)
 	if err != nil {
@@ -193,6 +293,15 @@ func (c *CompanyService) UpdateCompanyByID(companyID string, updatedCompany mode
 
 	filter := bson.M{"_id": _id, "isDeleted": false}
 
+	var existing models.Company
+	opts := options.FindOne().SetProjection(bson.M{"contact.companyEmail": 1})
+	if err := c.companyCollection.FindOne(context.Background(), filter, opts).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Company{}, errors.New("company not found or already deleted")
+		}
+		return models.Company{}, err
+	}
+
 	update := bson.M{
 		"$set": bson.M{
 			"companyName":  updatedCompany.CompanyName,
@@ -202,10 +311,34 @@ func (c *CompanyService) UpdateCompanyByID(companyID string, updatedCompany mode
 			"companyField": updatedCompany.CompanyField,
 		},
 	}
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	// The location follows the address
+	if location := c.companyLocation(updatedCompany); location != nil {
+		update["$set"].(bson.M)["location"] = location
+	} else {
+		update["$unset"] = bson.M{"location": ""}
+	}
+	// A new email has to be verified again before the company can sign in with it
+	newEmail := updatedCompany.Contact.CompanyEmail
+	emailChanged := !strings.EqualFold(newEmail, existing.Contact.CompanyEmail)
+	if emailChanged {
+		taken, err := companyEmailTaken(c.memberCollection, c.companyCollection, newEmail, _id)
+		if err != nil {
+			return models.Company{}, err
+		}
+		if taken {
+			return models.Company{}, ErrEmailTaken
+		}
+		update["$set"].(bson.M)["isVerified"] = false
+		if unset, ok := update["$unset"].(bson.M); ok {
+			unset["verifiedAt"] = ""
+		} else {
+			update["$unset"] = bson.M{"verifiedAt": ""}
+		}
+	}
+	updateOpts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
 	var updatedDoc models.Company
-	err = c.companyCollection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&updatedDoc)
+	err = c.companyCollection.FindOneAndUpdate(context.Background(), filter, update, updateOpts).Decode(&updatedDoc)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return models.Company{}, errors.New("company not found or already deleted")
@@ -213,6 +346,23 @@ func (c *CompanyService) UpdateCompanyByID(companyID string, updatedCompany mode
 		return models.Company{}, err
 	}
 
+	// Jobs carry the company name for full text search
+	_, err = c.jobCollection.UpdateMany(
+		context.Background(),
+		bson.M{"companyID": _id},
+		bson.M{"$set": bson.M{"search.company": utils.FoldText(updatedDoc.CompanyName)}},
+	)
+	if err != nil {
+		log.Printf("Error refreshing job search for company %s: %v", companyID, err)
+	}
+
+	if emailChanged {
+		if err := c.verification.SendVerificationEmail(constants.COMPANY, _id, newEmail); err != nil {
+			log.Printf("Error sending verification email to %s: %v", newEmail, err)
+		}
+	}
+
+	updatedDoc.Password = ""
 	return updatedDoc, nil
 }
 
@@ -500,8 +650,20 @@ func (c *CompanyService) UploadCompanyImage(link string, id string) error {
 	return nil
 }
 
-func (c *CompanyService) ChangeResumeStatus(resumeID string, status string) error {
+// ChangeResumeStatus sets the status of an application once, an empty companyID (admin) is not scoped
+func (c *CompanyService) ChangeResumeStatus(companyID string, resumeID string, status string) error {
 	_id, _ := primitive.ObjectIDFromHex(resumeID)
+	filter := bson.M{
+		"_id":      _id,
+		"isChange": bson.M{"$ne": true},
+	}
+	if companyID != "" {
+		companyObjectID, err := primitive.ObjectIDFromHex(companyID)
+		if err != nil {
+			return err
+		}
+		filter["companyID"] = companyObjectID
+	}
 
 	update := bson.M{
 		"$set": bson.M{
@@ -510,14 +672,7 @@ func (c *CompanyService) ChangeResumeStatus(resumeID string, status string) erro
 		},
 	}
 
-	result, err := c.careerApplyJob.UpdateOne(
-		context.Background(),
-		bson.M{
-			"_id":      _id,
-			"isChange": bson.M{"$ne": true},
-		},
-		update,
-	)
+	result, err := c.careerApplyJob.UpdateOne(context.Background(), filter, update)
 
 	if err != nil {
 		return err
@@ -529,54 +684,10 @@ func (c *CompanyService) ChangeResumeStatus(resumeID string, status string) erro
 
 	return nil
 }
-func (c *CompanyService) RequestPasswordResetCompany(email string) (string, error) {
-	var company models.Company
-
-	err := c.companyCollection.FindOne(context.Background(), bson.M{"contact.companyEmail": email}).Decode(&company)
-	if err != nil {
-		return "", fmt.Errorf("Không tìm thấy công ty với email %s: %v", email, err)
-	}
-
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	code := rng.Intn(9000) + 1000
-	verificationCode := fmt.Sprintf("%d", code)
-
-	subject := "Mã xác nhận khôi phục mật khẩu"
-	body := fmt.Sprintf("Mã xác nhận của bạn là: %s", verificationCode)
-	if err := SendEmail(email, subject, body); err != nil {
-		return "", err
-	}
-
-	_, err = c.companyCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": company.Id},
-		bson.M{"$set": bson.M{"verificationCode": verificationCode}},
-	)
-	if err != nil {
-		return "", fmt.Errorf("Lỗi khi cập nhật mã xác nhận: %v", err)
-	}
-
-	return verificationCode, nil
+func (c *CompanyService) RequestPasswordResetCompany(email string, ip string) error {
+	return c.passwordReset.RequestReset(constants.COMPANY, email, ip)
 }
 
 func (c *CompanyService) ResetPasswordCompany(email string, code string, newPassword string) error {
-	var company models.Company
-
-	err := c.companyCollection.FindOne(context.Background(), bson.M{"contact.companyEmail": email, "verificationCode": code}).Decode(&company)
-	if err != nil {
-		return fmt.Errorf("Sai mã xác nhận: %v", err)
-	}
-
-	hashedPassword := utils.EncodeToSHA(newPassword)
-
-	_, err = c.companyCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": company.Id},
-		bson.M{"$set": bson.M{"password": hashedPassword, "verificationCode": ""}},
-	)
-	if err != nil {
-		return fmt.Errorf("Lỗi khi khôi phục mật khẩu: %v", err)
-	}
-
-	return nil
+	return c.passwordReset.ResetPassword(constants.COMPANY, email, code, newPassword)
 }
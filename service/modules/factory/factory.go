@@ -5,6 +5,7 @@ import (
 	"hireforwork-server/db"
 	"hireforwork-server/service"
 	modules "hireforwork-server/service/modules"
+	"hireforwork-server/service/modules/audit"
 	auth "hireforwork-server/service/modules/auth"
 	job "hireforwork-server/service/modules/jobs"
 	observe "hireforwork-server/service/observe"
@@ -45,6 +46,9 @@ var serviceCreators = map[string]ServiceCreator{
 	"tech":     func(deps *ServiceDependencies) interface{} { return modules.NewTechService(deps.DB) },
 	"category": func(deps *ServiceDependencies) interface{} { return modules.NewCategoryService(deps.DB) },
 	"field":    func(deps *ServiceDependencies) interface{} { return modules.NewFieldService(deps.DB) },
+	"audit":    func(deps *ServiceDependencies) interface{} { return audit.NewLogger(deps.DB) },
+	"report":   func(deps *ServiceDependencies) interface{} { return modules.NewReportService(deps.DB) },
+	"static":   func(deps *ServiceDependencies) interface{} { return modules.NewStaticService(deps.DB) },
 	"observe": func(deps *ServiceDependencies) interface{} {
 		return observe.NewJobEventManager()
 	},
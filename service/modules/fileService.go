@@ -78,7 +78,7 @@ func UploadFile(ctx context.Context, file multipart.File, header *multipart.File
 	}
 	attrs, err := object.Attrs(ctx)
 	if err != nil {
-		return "", fmt.Errorf("Error when retrieve object: ", err.Error())
+		return "", fmt.Errorf("Error when retrieve object: %v", err)
 	}
 	publicURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, attrs.Name)
 
@@ -0,0 +1,184 @@
+package geo
+
+import (
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"regexp"
+	"strings"
+)
+
+// place is a province, city or district with the names people write it by
+type place struct {
+	names    []string
+	lat, lng float64
+}
+
+// vietnamPlaces are the 63 provinces at their administrative centre, the main cities
+// and the urban districts of Hồ Chí Minh City, Hà Nội and Đà Nẵng
+var vietnamPlaces = []place{
+	// Provinces and centrally governed cities
+	{[]string{"An Giang", "Long Xuyên"}, 10.386, 105.435},
+	{[]string{"Bà Rịa - Vũng Tàu", "Bà Rịa Vũng Tàu", "BRVT", "Bà Rịa"}, 10.496, 107.168},
+	{[]string{"Bắc Giang"}, 21.273, 106.194},
+	{[]string{"Bắc Kạn"}, 22.147, 105.834},
+	{[]string{"Bạc Liêu"}, 9.294, 105.727},
+	{[]string{"Bắc Ninh"}, 21.186, 106.076},
+	{[]string{"Bến Tre"}, 10.241, 106.376},
+	{[]string{"Bình Định", "Quy Nhơn"}, 13.782, 109.219},
+	{[]string{"Bình Dương", "Thủ Dầu Một"}, 10.980, 106.651},
+	{[]string{"Bình Phước", "Đồng Xoài"}, 11.535, 106.883},
+	{[]string{"Bình Thuận", "Phan Thiết"}, 10.928, 108.102},
+	{[]string{"Cà Mau"}, 9.176, 105.150},
+	{[]string{"Cần Thơ"}, 10.045, 105.747},
+	{[]string{"Cao Bằng"}, 22.666, 106.258},
+	{[]string{"Đà Nẵng", "Da Nang"}, 16.054, 108.202},
+	{[]string{"Đắk Lắk", "Dak Lak", "Buôn Ma Thuột"}, 12.666, 108.038},
+	{[]string{"Đắk Nông", "Dak Nong", "Gia Nghĩa"}, 12.004, 107.690},
+	{[]string{"Điện Biên", "Điện Biên Phủ"}, 21.386, 103.017},
+	{[]string{"Đồng Nai", "Biên Hòa", "Biên Hoà"}, 10.957, 106.843},
+	{[]string{"Đồng Tháp", "Cao Lãnh"}, 10.460, 105.633},
+	{[]string{"Gia Lai", "Pleiku"}, 13.983, 108.000},
+	{[]string{"Hà Giang"}, 22.823, 104.984},
+	{[]string{"Hà Nam", "Phủ Lý"}, 20.541, 105.914},
+	{[]string{"Hà Nội", "Hanoi", "HN"}, 21.028, 105.834},
+	{[]string{"Hà Tĩnh"}, 18.343, 105.906},
+	{[]string{"Hải Dương"}, 20.940, 106.331},
+	{[]string{"Hải Phòng", "Haiphong"}, 20.845, 106.688},
+	{[]string{"Hậu Giang", "Vị Thanh"}, 9.784, 105.470},
+	{[]string{"Hòa Bình", "Hoà Bình"}, 20.817, 105.338},
+	{[]string{"Hưng Yên"}, 20.646, 106.051},
+	{[]string{"Khánh Hòa", "Khánh Hoà", "Nha Trang"}, 12.238, 109.197},
+	{[]string{"Kiên Giang", "Rạch Giá", "Phú Quốc"}, 10.012, 105.081},
+	{[]string{"Kon Tum"}, 14.350, 108.000},
+	{[]string{"Lai Châu"}, 22.396, 103.458},
+	{[]string{"Lâm Đồng", "Đà Lạt", "Da Lat"}, 11.940, 108.458},
+	{[]string{"Lạng Sơn"}, 21.853, 106.761},
+	{[]string{"Lào Cai", "Sa Pa"}, 22.486, 103.971},
+	{[]string{"Long An", "Tân An"}, 10.535, 106.413},
+	{[]string{"Nam Định"}, 20.434, 106.177},
+	{[]string{"Nghệ An", "Vinh"}, 18.679, 105.681},
+	{[]string{"Ninh Bình"}, 20.251, 105.975},
+	{[]string{"Ninh Thuận", "Phan Rang", "Phan Rang - Tháp Chàm"}, 11.565, 108.988},
+	{[]string{"Phú Thọ", "Việt Trì"}, 21.323, 105.402},
+	{[]string{"Phú Yên", "Tuy Hòa", "Tuy Hoà"}, 13.096, 109.321},
+	{[]string{"Quảng Bình", "Đồng Hới"}, 17.468, 106.622},
+	{[]string{"Quảng Nam", "Tam Kỳ", "Hội An"}, 15.573, 108.474},
+	{[]string{"Quảng Ngãi"}, 15.120, 108.792},
+	{[]string{"Quảng Ninh", "Hạ Long"}, 20.959, 107.042},
+	{[]string{"Quảng Trị", "Đông Hà"}, 16.816, 107.100},
+	{[]string{"Sóc Trăng"}, 9.603, 105.980},
+	{[]string{"Sơn La"}, 21.327, 103.914},
+	{[]string{"Tây Ninh"}, 11.310, 106.098},
+	{[]string{"Thái Bình"}, 20.447, 106.336},
+	{[]string{"Thái Nguyên"}, 21.594, 105.848},
+	{[]string{"Thanh Hóa", "Thanh Hoá"}, 19.807, 105.776},
+	{[]string{"Thừa Thiên Huế", "Thừa Thiên - Huế", "Huế"}, 16.463, 107.590},
+	{[]string{"Tiền Giang", "Mỹ Tho"}, 10.360, 106.360},
+	{[]string{"Hồ Chí Minh", "TPHCM", "HCM", "HCMC", "Sài Gòn", "Saigon", "Ho Chi Minh City"}, 10.776, 106.701},
+	{[]string{"Trà Vinh"}, 9.935, 106.345},
+	{[]string{"Tuyên Quang"}, 21.823, 105.214},
+	{[]string{"Vĩnh Long"}, 10.254, 105.972},
+	{[]string{"Vĩnh Phúc", "Vĩnh Yên"}, 21.309, 105.605},
+	{[]string{"Yên Bái"}, 21.705, 104.875},
+
+	// Hồ Chí Minh City
+	{[]string{"Quận 1"}, 10.7756, 106.7019},
+	{[]string{"Quận 2"}, 10.7872, 106.7498},
+	{[]string{"Quận 3"}, 10.7843, 106.6844},
+	{[]string{"Quận 4"}, 10.7579, 106.7013},
+	{[]string{"Quận 5"}, 10.7540, 106.6634},
+	{[]string{"Quận 6"}, 10.7480, 106.6352},
+	{[]string{"Quận 7"}, 10.7340, 106.7216},
+	{[]string{"Quận 8"}, 10.7240, 106.6286},
+	{[]string{"Quận 9"}, 10.8428, 106.8287},
+	{[]string{"Quận 10"}, 10.7746, 106.6679},
+	{[]string{"Quận 11"}, 10.7629, 106.6501},
+	{[]string{"Quận 12"}, 10.8672, 106.6413},
+	{[]string{"Bình Thạnh"}, 10.8106, 106.7091},
+	{[]string{"Phú Nhuận"}, 10.7992, 106.6803},
+	{[]string{"Tân Bình"}, 10.8015, 106.6527},
+	{[]string{"Tân Phú"}, 10.7900, 106.6281},
+	{[]string{"Gò Vấp"}, 10.8387, 106.6653},
+	{[]string{"Bình Tân"}, 10.7652, 106.6039},
+	{[]string{"Thủ Đức"}, 10.8494, 106.7537},
+	{[]string{"Nhà Bè"}, 10.6950, 106.7046},
+	{[]string{"Hóc Môn"}, 10.8863, 106.5923},
+	{[]string{"Củ Chi"}, 10.9735, 106.4936},
+	{[]string{"Bình Chánh"}, 10.6874, 106.5939},
+	{[]string{"Cần Giờ"}, 10.4113, 106.9547},
+
+	// Hà Nội
+	{[]string{"Ba Đình"}, 21.0340, 105.8147},
+	{[]string{"Hoàn Kiếm"}, 21.0288, 105.8525},
+	{[]string{"Tây Hồ"}, 21.0702, 105.8188},
+	{[]string{"Long Biên"}, 21.0362, 105.8860},
+	{[]string{"Cầu Giấy"}, 21.0362, 105.7906},
+	{[]string{"Đống Đa"}, 21.0181, 105.8291},
+	{[]string{"Hai Bà Trưng"}, 21.0059, 105.8575},
+	{[]string{"Hoàng Mai"}, 20.9740, 105.8630},
+	{[]string{"Thanh Xuân"}, 20.9936, 105.8048},
+	{[]string{"Nam Từ Liêm"}, 21.0120, 105.7650},
+	{[]string{"Bắc Từ Liêm"}, 21.0700, 105.7600},
+	{[]string{"Hà Đông"}, 20.9714, 105.7788},
+
+	// Đà Nẵng
+	{[]string{"Hải Châu"}, 16.0470, 108.2200},
+	{[]string{"Thanh Khê"}, 16.0640, 108.1900},
+	{[]string{"Sơn Trà"}, 16.0800, 108.2400},
+	{[]string{"Ngũ Hành Sơn"}, 16.0000, 108.2500},
+	{[]string{"Liên Chiểu"}, 16.0750, 108.1500},
+	{[]string{"Cẩm Lệ"}, 16.0150, 108.2000},
+}
+
+var (
+	placePunctuation = strings.NewReplacer(".", " ", "-", " ", "_", " ", "/", " ")
+	// "Q1", "Q.1", "Quận 1" and "District 1" name the same district
+	numberedDistrict = regexp.MustCompile(`^(?:quan|district|q) ?(\d+)$`)
+	// Administrative words people may or may not write around a name
+	placePrefixes = []string{"thanh pho ", "tp ", "tinh ", "quan ", "huyen ", "thi xa ", "province ", "city ", "district "}
+	placeSuffixes = []string{" city", " province", " district"}
+)
+
+// placeKey normalizes a place name so spelling, case and diacritics do not matter
+func placeKey(name string) string {
+	key := utils.FoldText(placePunctuation.Replace(name))
+	if match := numberedDistrict.FindStringSubmatch(key); match != nil {
+		return "quan " + match[1]
+	}
+	for _, prefix := range placePrefixes {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	for _, suffix := range placeSuffixes {
+		key = strings.TrimSuffix(key, suffix)
+	}
+	return key
+}
+
+// Gazetteer geocodes Vietnamese provinces, cities and districts without any network call
+type Gazetteer struct {
+	places map[string]models.GeoPoint
+}
+
+func NewGazetteer() *Gazetteer {
+	places := map[string]models.GeoPoint{}
+	for _, entry := range vietnamPlaces {
+		for _, name := range entry.names {
+			places[placeKey(name)] = Point(entry.lat, entry.lng)
+		}
+	}
+	return &Gazetteer{places: places}
+}
+
+// Geocode matches a place name, or the most precise known part of a comma separated
+// address such as "12 Lê Lợi, Quận 1, TP. Hồ Chí Minh"
+func (g *Gazetteer) Geocode(name string) (models.GeoPoint, bool) {
+	if point, ok := g.places[placeKey(name)]; ok {
+		return point, true
+	}
+	for _, part := range strings.Split(name, ",") {
+		if point, ok := g.places[placeKey(part)]; ok {
+			return point, true
+		}
+	}
+	return models.GeoPoint{}, false
+}
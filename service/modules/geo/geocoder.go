@@ -0,0 +1,67 @@
+package geo
+
+import (
+	"hireforwork-server/models"
+	"strconv"
+	"strings"
+)
+
+/*
+1. Geocoder turns a place typed by a user or a company into a point
+2. Gazetteer is the offline default, another provider only has to implement Geocode
+*/
+type Geocoder interface {
+	Geocode(place string) (models.GeoPoint, bool)
+}
+
+// EarthRadiusKm is the mean radius used for distances and $centerSphere radians
+const EarthRadiusKm = 6378.1
+
+// Point builds the GeoJSON point of a latitude and longitude
+func Point(lat float64, lng float64) models.GeoPoint {
+	return models.GeoPoint{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+// ParsePoint reads a "lat,lng" pair
+func ParsePoint(value string) (models.GeoPoint, bool) {
+	latValue, lngValue, found := strings.Cut(value, ",")
+	if !found {
+		return models.GeoPoint{}, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latValue), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return models.GeoPoint{}, false
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(lngValue), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return models.GeoPoint{}, false
+	}
+	return Point(lat, lng), true
+}
+
+// Resolve reads a "lat,lng" pair or geocodes a place name
+func Resolve(geocoder Geocoder, place string) (models.GeoPoint, bool) {
+	if point, ok := ParsePoint(place); ok {
+		return point, true
+	}
+	return geocoder.Geocode(place)
+}
+
+// Locate geocodes every place once, places the geocoder does not know are skipped
+func Locate(geocoder Geocoder, places []string) []models.GeoPoint {
+	points := []models.GeoPoint{}
+	seen := map[[2]float64]bool{}
+	for _, place := range places {
+		point, ok := geocoder.Geocode(place)
+		if !ok {
+			continue
+		}
+		key := [2]float64{point.Coordinates[0], point.Coordinates[1]}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		points = append(points, point)
+	}
+	return points
+}
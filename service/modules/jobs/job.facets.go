@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"hireforwork-server/interfaces"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// salaryFacet names the salary buckets among the facets
+const salaryFacet = "salary"
+
+// maxFacetValues keeps the most common values of a facet, the long tail is dropped
+const maxFacetValues = 50
+
+// jobFacets are the fields the search sidebar counts jobs by, besides salary
+var jobFacets = []string{"workingLocation", "jobCategory", "jobLevel", "jobTech", "workArrangement", "employmentType"}
+
+// facetPaths locates the facets not named after a top level field, facet names cannot
+// hold dots
+var facetPaths = map[string]string{"workArrangement": "workArrangement.type"}
+
+func facetPath(name string) string {
+	if path, ok := facetPaths[name]; ok {
+		return path
+	}
+	return name
+}
+
+// salaryBuckets are the lower bounds of the monthly VND ranges counted by monthlySalary,
+// the last bucket is open ended
+var salaryBuckets = bson.A{int64(0), int64(10_000_000), int64(20_000_000), int64(30_000_000), int64(50_000_000)}
+
+// facetFilters returns the sidebar filters of a search keyed by the facet they narrow
+func facetFilters(filter interfaces.IJobFilter) map[string]bson.M {
+	filters := map[string]bson.M{}
+	//filter by working location
+	if len(filter.WorkingLocation) > 0 {
+		filters["workingLocation"] = bson.M{"workingLocation": bson.M{"$in": filter.WorkingLocation}}
+	}
+	//filter by category
+	if len(filter.JobCategory) > 0 {
+		filters["jobCategory"] = bson.M{"jobCategory": bson.M{"$all": filter.JobCategory}}
+	}
+	//filter by job level
+	if filter.JobLevel != "" {
+		filters["jobLevel"] = bson.M{"jobLevel": filter.JobLevel}
+	}
+	//filter by tech
+	if len(filter.JobTech) > 0 {
+		filters["jobTech"] = bson.M{"jobTech": bson.M{"$in": filter.JobTech}}
+	}
+	//filter by work arrangement
+	if len(filter.WorkArrangement) > 0 {
+		filters["workArrangement"] = bson.M{"workArrangement.type": bson.M{"$in": filter.WorkArrangement}}
+	}
+	//filter by employment type
+	if len(filter.EmploymentType) > 0 {
+		filters["employmentType"] = bson.M{"employmentType": bson.M{"$in": filter.EmploymentType}}
+	}
+	//filter by salary, bounds are monthly VND
+	if filter.SalaryFrom != 0 || filter.SalaryTo != 0 {
+		filters[salaryFacet] = salaryOverlap(filter.SalaryFrom, filter.SalaryTo)
+	}
+	return filters
+}
+
+// facetMatch applies every sidebar filter but the one of the facet being counted, so
+// the sidebar shows what picking another value would return
+func facetMatch(filters map[string]bson.M, except string) bson.D {
+	clauses := bson.A{}
+	for _, name := range append(jobFacets, salaryFacet) {
+		if clause, ok := filters[name]; ok && name != except {
+			clauses = append(clauses, clause)
+		}
+	}
+	if len(clauses) == 0 {
+		return bson.D{{"$match", bson.D{}}}
+	}
+	return bson.D{{"$match", bson.D{{"$and", clauses}}}}
+}
+
+// valueFacet counts the jobs per value of a facet, array fields count every element
+func valueFacet(filters map[string]bson.M, name string) []bson.D {
+	field := facetPath(name)
+	return []bson.D{
+		facetMatch(filters, name),
+		{{"$unwind", "$" + field}},
+		{{"$match", bson.D{{field, bson.D{{"$nin", bson.A{nil, ""}}}}}}},
+		{{"$group", bson.D{{"_id", "$" + field}, {"count", bson.D{{"$sum", 1}}}}}},
+		{{"$sort", bson.D{{"count", -1}, {"_id", 1}}}},
+		{{"$limit", maxFacetValues}},
+		{{"$project", bson.D{{"_id", 0}, {"value", "$_id"}, {"count", 1}}}},
+	}
+}
+
+// salaryFacetStages counts the jobs with a published salary per salaryBuckets range
+func salaryFacetStages(filters map[string]bson.M) []bson.D {
+	last := salaryBuckets[len(salaryBuckets)-1]
+	return []bson.D{
+		facetMatch(filters, salaryFacet),
+		{{"$match", bson.D{{"$expr", bson.D{{"$gt", bson.A{monthlySalary, 0}}}}}}},
+		{{"$bucket", bson.D{
+			{"groupBy", monthlySalary},
+			{"boundaries", salaryBuckets},
+			// Everything above the last bound falls in the open ended bucket
+			{"default", last},
+			{"output", bson.D{{"count", bson.D{{"$sum", 1}}}}},
+		}}},
+		{{"$project", bson.D{
+			{"_id", 0},
+			{"from", "$_id"},
+			{"to", bson.D{{"$arrayElemAt", bson.A{
+				salaryBuckets,
+				bson.D{{"$add", bson.A{bson.D{{"$indexOfArray", bson.A{salaryBuckets, "$_id"}}}, 1}}},
+			}}}},
+			{"count", 1},
+		}}},
+	}
+}
+
+// jobFacetStage pages the matching jobs with pageStages and counts them per facet in
+// a single pass
+func jobFacetStage(filters map[string]bson.M, pageStages []bson.D) bson.D {
+	matchAll := facetMatch(filters, "")
+	data := append([]bson.D{matchAll}, pageStages...)
+	data = append(data, bson.D{{"$addFields", bson.D{
+		{"companyName", "$companyDetails.companyName"},
+		{"companyImage", "$companyDetails.companyImage"},
+	}}})
+	facets := bson.D{
+		{"totalCount", []bson.D{matchAll, {{"$count", "count"}}}},
+		{"data", data},
+	}
+	for _, field := range jobFacets {
+		facets = append(facets, bson.E{field, valueFacet(filters, field)})
+	}
+	facets = append(facets, bson.E{salaryFacet, salaryFacetStages(filters)})
+	return bson.D{{"$facet", facets}}
+}
+
+// facetProjection gathers the facet counts under a single "facets" field
+func facetProjection() bson.D {
+	projection := bson.D{}
+	for _, field := range append(jobFacets, salaryFacet) {
+		projection = append(projection, bson.E{field, "$" + field})
+	}
+	return projection
+}
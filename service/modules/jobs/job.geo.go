@@ -0,0 +1,197 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireforwork-server/models"
+	"hireforwork-server/service/modules/geo"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrUnknownPlace  = errors.New("Không xác định được địa điểm")
+	ErrInvalidRadius = errors.New("Bán kính tìm kiếm không hợp lệ")
+)
+
+const (
+	defaultRadiusKm = 15
+	maxRadiusKm     = 200
+)
+
+// geoIndex lets $geoWithin skip the jobs far from the searched place
+var geoIndex = mongo.IndexModel{Keys: bson.D{{"geoPoints", "2dsphere"}}}
+
+// nearFilter resolves the place of a distance search and the circle jobs must fall in
+func (j *JobRepository) nearFilter(near string, radiusKm float64) (*models.GeoPoint, bson.M, error) {
+	if near == "" {
+		return nil, nil, nil
+	}
+	// Written so NaN is rejected as well
+	if !(radiusKm >= 0 && radiusKm <= maxRadiusKm) {
+		return nil, nil, ErrInvalidRadius
+	}
+	if radiusKm == 0 {
+		radiusKm = defaultRadiusKm
+	}
+	center, ok := geo.Resolve(j.geocoder, near)
+	if !ok {
+		return nil, nil, ErrUnknownPlace
+	}
+	within := bson.M{"$geoWithin": bson.M{
+		"$centerSphere": bson.A{center.Coordinates, radiusKm / geo.EarthRadiusKm},
+	}}
+	return &center, within, nil
+}
+
+// distanceKm is the great circle distance in km from center to the closest point of the
+// job, rounded to 10 m. $geoNear would compute it but must be the first stage, as $text.
+func distanceKm(center models.GeoPoint) bson.D {
+	lat := center.Coordinates[1] * math.Pi / 180
+	lng := center.Coordinates[0] * math.Pi / 180
+	pointLng := bson.D{{"$degreesToRadians", bson.D{{"$arrayElemAt", bson.A{"$$point.coordinates", 0}}}}}
+	pointLat := bson.D{{"$degreesToRadians", bson.D{{"$arrayElemAt", bson.A{"$$point.coordinates", 1}}}}}
+	halfSin := func(from interface{}, to float64) bson.D {
+		return bson.D{{"$sin", bson.D{{"$divide", bson.A{bson.D{{"$subtract", bson.A{from, to}}}, 2}}}}}
+	}
+	// Haversine formula
+	haversine := bson.D{{"$add", bson.A{
+		bson.D{{"$pow", bson.A{halfSin(pointLat, lat), 2}}},
+		bson.D{{"$multiply", bson.A{
+			math.Cos(lat),
+			bson.D{{"$cos", pointLat}},
+			bson.D{{"$pow", bson.A{halfSin(pointLng, lng), 2}}},
+		}}},
+	}}}
+	distance := bson.D{{"$multiply", bson.A{
+		2 * geo.EarthRadiusKm,
+		bson.D{{"$asin", bson.D{{"$sqrt", bson.D{{"$min", bson.A{haversine, 1}}}}}}},
+	}}}
+	return bson.D{{"$round", bson.A{
+		bson.D{{"$min", bson.D{{"$map", bson.D{
+			{"input", bson.D{{"$ifNull", bson.A{"$geoPoints", bson.A{}}}}},
+			{"as", "point"},
+			{"in", distance},
+		}}}}},
+		2,
+	}}}
+}
+
+// companyDetails reads the name and the location of the company posting a job, both are
+// stored with the job for search
+func (j *JobRepository) companyDetails(companyID primitive.ObjectID) (string, *models.GeoPoint) {
+	var company models.Company
+	opts := options.FindOne().SetProjection(bson.M{"companyName": 1, "location": 1, "contact.companyAddress": 1})
+	if err := j.companyCollection.FindOne(context.Background(), bson.M{"_id": companyID}, opts).Decode(&company); err != nil {
+		log.Printf("Error reading company %s for job search: %v", companyID.Hex(), err)
+	}
+	return company.CompanyName, j.companyLocation(company.Location, company.Contact.CompanyAddress)
+}
+
+// companyLocation falls back to geocoding the address of companies saved before they
+// carried a location
+func (j *JobRepository) companyLocation(location *models.GeoPoint, address string) *models.GeoPoint {
+	if location != nil {
+		return location
+	}
+	if point, ok := j.geocoder.Geocode(address); ok {
+		return &point
+	}
+	return nil
+}
+
+// geoPoints locates the working locations of a job, a job listing none the geocoder
+// knows is placed at its company
+func (j *JobRepository) geoPoints(workingLocation []string, companyLocation *models.GeoPoint) []models.GeoPoint {
+	points := geo.Locate(j.geocoder, workingLocation)
+	if len(points) == 0 && companyLocation != nil {
+		points = append(points, *companyLocation)
+	}
+	return points
+}
+
+// refreshGeoPoints locates a job again after its working locations changed
+func (j *JobRepository) refreshGeoPoints(job *models.Jobs) {
+	points := geo.Locate(j.geocoder, job.WorkingLocation)
+	if len(points) == 0 {
+		_, location := j.companyDetails(job.CompanyID)
+		points = j.geoPoints(nil, location)
+	}
+	_, err := j.jobCollection.UpdateOne(context.Background(), bson.M{"_id": job.Id}, bson.M{"$set": bson.M{"geoPoints": points}})
+	if err != nil {
+		log.Printf("Error locating job %s: %v", job.Id.Hex(), err)
+		return
+	}
+	job.GeoPoints = points
+}
+
+// backfillGeoPoints locates jobs saved before they carried points, jobs nowhere to be
+// found get an empty list so they are not read again
+func (j *JobRepository) backfillGeoPoints() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"geoPoints", bson.D{{"$exists", false}}}}}},
+		{{"$project", bson.D{{"workingLocation", 1}, {"companyID", 1}}}},
+		{{"$lookup", bson.D{
+			{"from", "Company"},
+			{"localField", "companyID"},
+			{"foreignField", "_id"},
+			{"as", "company"},
+		}}},
+		{{"$set", bson.D{
+			{"companyLocation", bson.D{{"$first", "$company.location"}}},
+			{"companyAddress", bson.D{{"$first", "$company.contact.companyAddress"}}},
+		}}},
+		{{"$unset", bson.A{"company"}}},
+	}
+	cursor, err := j.jobCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("reading jobs to backfill locations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	writes := []mongo.WriteModel{}
+	var failed error
+	flush := func() {
+		if len(writes) == 0 {
+			return
+		}
+		if _, err := j.jobCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			failed = fmt.Errorf("backfilling job locations: %w", err)
+		}
+		writes = writes[:0]
+	}
+	for cursor.Next(ctx) {
+		var job struct {
+			Id              primitive.ObjectID `bson:"_id"`
+			WorkingLocation []string           `bson:"workingLocation"`
+			CompanyLocation *models.GeoPoint   `bson:"companyLocation"`
+			CompanyAddress  string             `bson:"companyAddress"`
+		}
+		if err := cursor.Decode(&job); err != nil {
+			log.Printf("Error decoding job to backfill locations: %v", err)
+			continue
+		}
+		points := j.geoPoints(job.WorkingLocation, j.companyLocation(job.CompanyLocation, job.CompanyAddress))
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": job.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"geoPoints": points}}))
+		if len(writes) >= searchBackfillBatch {
+			flush()
+		}
+	}
+	flush()
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return failed
+}
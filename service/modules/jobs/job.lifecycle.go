@@ -0,0 +1,436 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireforwork-server/constants"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidJobTransition = errors.New("Không thể chuyển bài đăng sang trạng thái này")
+	ErrInvalidJobSchedule   = errors.New("Thời gian đăng hoặc ngày hết hạn của bài đăng không hợp lệ")
+	ErrJobNotOpen           = errors.New("Bài đăng không còn nhận hồ sơ")
+	ErrInvalidExtendLink    = errors.New("Liên kết gia hạn không hợp lệ hoặc đã được sử dụng")
+)
+
+const extendJobPurpose = "extend-job"
+
+// extendLinkGrace keeps the link of an expiry reminder usable for a while after the
+// job expired, extending then republishes it
+const extendLinkGrace = 7 * 24 * time.Hour
+
+// jobTransitions lists the statuses a job can be moved to from each status. Expiry is
+// left to the scheduler, an expired job is republished with a new expire date.
+var jobTransitions = map[string][]string{
+	constants.JOB_DRAFT:     {constants.JOB_SCHEDULED, constants.JOB_PUBLISHED, constants.JOB_ARCHIVED},
+	constants.JOB_SCHEDULED: {constants.JOB_DRAFT, constants.JOB_PUBLISHED, constants.JOB_ARCHIVED},
+	constants.JOB_PUBLISHED: {constants.JOB_PAUSED, constants.JOB_CLOSED},
+	constants.JOB_PAUSED:    {constants.JOB_PUBLISHED, constants.JOB_CLOSED},
+	constants.JOB_CLOSED:    {constants.JOB_PUBLISHED, constants.JOB_ARCHIVED},
+	constants.JOB_EXPIRED:   {constants.JOB_PUBLISHED, constants.JOB_ARCHIVED},
+	constants.JOB_ARCHIVED:  {},
+}
+
+func canTransition(from string, to string) bool {
+	for _, status := range jobTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// jobStatus reads the status of a job, deriving it for jobs saved before statuses existed
+func jobStatus(job models.Jobs) string {
+	switch {
+	case job.Status != "":
+		return job.Status
+	case job.IsClosed:
+		return constants.JOB_CLOSED
+	case !job.ExpireDate.Time().After(time.Now()):
+		return constants.JOB_EXPIRED
+	default:
+		return constants.JOB_PUBLISHED
+	}
+}
+
+// isClosedStatus keeps the legacy isClosed flag meaningful for older clients
+func isClosedStatus(status string) bool {
+	return status == constants.JOB_CLOSED || status == constants.JOB_EXPIRED || status == constants.JOB_ARCHIVED
+}
+
+// publicJobFilter matches the jobs careers can browse and apply to. Jobs created
+// before statuses existed count as published while the background backfill has not
+// reached them yet, unless they were closed. $in rather than $or so that it still
+// combines with $text.
+func publicJobFilter() bson.M {
+	return bson.M{
+		"isDeleted":  false,
+		"status":     bson.M{"$in": bson.A{constants.JOB_PUBLISHED, nil}},
+		"isClosed":   bson.M{"$ne": true},
+		"expireDate": bson.M{"$gt": time.Now()},
+	}
+}
+
+// validateSchedule checks the dates a job needs to enter status: a published job has
+// to expire in the future, a scheduled one after it goes live
+func validateSchedule(status string, publishAt time.Time, expireDate time.Time, now time.Time) error {
+	switch status {
+	case constants.JOB_PUBLISHED:
+		if !expireDate.After(now) {
+			return ErrInvalidJobSchedule
+		}
+	case constants.JOB_SCHEDULED:
+		if !publishAt.After(now) || !expireDate.After(publishAt) {
+			return ErrInvalidJobSchedule
+		}
+	}
+	return nil
+}
+
+// prepareNewJob sets the lifecycle of a job being created. Without a status the job is
+// published right away, or scheduled when publishAt lies in the future.
+func prepareNewJob(job *models.Jobs, now time.Time) error {
+	switch job.Status {
+	case "":
+		job.Status = constants.JOB_PUBLISHED
+		if job.PublishAt.Time().After(now) {
+			job.Status = constants.JOB_SCHEDULED
+		}
+	case constants.JOB_DRAFT, constants.JOB_SCHEDULED, constants.JOB_PUBLISHED:
+	default:
+		return ErrInvalidJobTransition
+	}
+	if err := validateSchedule(job.Status, job.PublishAt.Time(), job.ExpireDate.Time(), now); err != nil {
+		return err
+	}
+
+	if job.Status == constants.JOB_PUBLISHED {
+		job.PublishAt = 0
+		job.PublishedAt = primitive.NewDateTimeFromTime(now)
+	}
+	job.IsClosed = false
+	job.ExpiryNotifiedAt = 0
+	return nil
+}
+
+// backfillStatus gives jobs saved before statuses existed the status they behaved as.
+// They all went live when created, so that is when they count as published.
+func (j *JobRepository) backfillStatus() error {
+	for _, step := range []struct {
+		filter bson.M
+		status string
+	}{
+		{bson.M{"isClosed": true}, constants.JOB_CLOSED},
+		{bson.M{"expireDate": bson.M{"$not": bson.M{"$gt": time.Now()}}}, constants.JOB_EXPIRED},
+		{bson.M{}, constants.JOB_PUBLISHED},
+	} {
+		step.filter["status"] = bson.M{"$exists": false}
+		update := mongo.Pipeline{{{"$set", bson.D{
+			{"status", step.status},
+			{"isClosed", isClosedStatus(step.status)},
+			{"publishedAt", "$createAt"},
+		}}}}
+		if _, err := j.jobCollection.UpdateMany(context.Background(), step.filter, update); err != nil {
+			return fmt.Errorf("backfilling job status %s: %w", step.status, err)
+		}
+	}
+	return nil
+}
+
+func (j *JobRepository) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := j.jobCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"status", 1}, {"expireDate", 1}}},
+		{Keys: bson.D{{"status", 1}, {"publishAt", 1}}},
+		textIndex,
+		geoIndex,
+	})
+	if err != nil {
+		log.Printf("Error creating job indexes: %v", err)
+	}
+}
+
+// ChangeStatus moves a job owned by companyID (any job when companyID is empty) along
+// the lifecycle. Observers hear about a job the first time it is published.
+func (j *JobRepository) ChangeStatus(companyID string, jobID string, request interfaces.IJobStatusRequest) (models.Jobs, error) {
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return models.Jobs{}, ErrJobNotFound
+	}
+	filter, err := ownedJobFilter(_id, companyID)
+	if err != nil {
+		return models.Jobs{}, err
+	}
+
+	var job models.Jobs
+	err = j.jobCollection.FindOne(context.Background(), filter).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return models.Jobs{}, ErrJobNotFound
+	}
+	if err != nil {
+		return models.Jobs{}, err
+	}
+	// Only an admin can touch a job a moderator closed, republishing it lifts the lock
+	if job.ClosedByAdmin && companyID != "" {
+		return models.Jobs{}, ErrJobClosedByAdmin
+	}
+	if !canTransition(jobStatus(job), request.Status) {
+		return models.Jobs{}, ErrInvalidJobTransition
+	}
+
+	now := time.Now()
+	expireDate := job.ExpireDate.Time()
+	if !request.ExpireDate.IsZero() {
+		expireDate = request.ExpireDate
+	}
+	if err := validateSchedule(request.Status, request.PublishAt, expireDate, now); err != nil {
+		return models.Jobs{}, err
+	}
+
+	set := bson.M{"status": request.Status, "isClosed": isClosedStatus(request.Status)}
+	unset := bson.M{}
+	if !request.ExpireDate.IsZero() {
+		// A new expiry gets its own reminder
+		set["expireDate"] = primitive.NewDateTimeFromTime(expireDate)
+		unset["expiryNotifiedAt"] = ""
+	}
+	switch request.Status {
+	case constants.JOB_SCHEDULED:
+		set["publishAt"] = primitive.NewDateTimeFromTime(request.PublishAt)
+	case constants.JOB_DRAFT:
+		unset["publishAt"] = ""
+	case constants.JOB_PUBLISHED:
+		unset["publishAt"] = ""
+		unset["closedByAdmin"] = ""
+		unset["closeReason"] = ""
+		if job.PublishedAt == 0 {
+			set["publishedAt"] = primitive.NewDateTimeFromTime(now)
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	// The status read above guards against a concurrent change, e.g. by the scheduler
+	if job.Status == "" {
+		filter["status"] = bson.M{"$exists": false}
+	} else {
+		filter["status"] = job.Status
+	}
+	var updated models.Jobs
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = j.jobCollection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return models.Jobs{}, ErrInvalidJobTransition
+	}
+	if err != nil {
+		return models.Jobs{}, err
+	}
+	j.cache.Flush()
+
+	if request.Status == constants.JOB_PUBLISHED && job.PublishedAt == 0 {
+		j.notifier.Notify(&updated)
+	}
+	return updated, nil
+}
+
+// extendToken signs the one-click extend link of a reminder. It is bound to the expire
+// date it was sent for, so the link extends the job once.
+func (j *JobRepository) extendToken(job models.Jobs, now time.Time) string {
+	subject := job.Id.Hex() + ":" + strconv.FormatInt(int64(job.ExpireDate), 10)
+	return utils.SignToken(j.secret, extendJobPurpose, subject, job.ExpireDate.Time().Sub(now)+extendLinkGrace)
+}
+
+// ExtendJob redeems the link of an expiry reminder, pushing the expire date back by the
+// configured period and republishing the job if it expired meanwhile
+func (j *JobRepository) ExtendJob(token string) (models.Jobs, error) {
+	subject, err := utils.VerifySignedToken(j.secret, token, extendJobPurpose)
+	if err != nil {
+		return models.Jobs{}, ErrInvalidExtendLink
+	}
+	id, expireAt, found := strings.Cut(subject, ":")
+	if !found {
+		return models.Jobs{}, ErrInvalidExtendLink
+	}
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return models.Jobs{}, ErrInvalidExtendLink
+	}
+	expireMillis, err := strconv.ParseInt(expireAt, 10, 64)
+	if err != nil {
+		return models.Jobs{}, ErrInvalidExtendLink
+	}
+
+	now := time.Now()
+	expireDate := primitive.DateTime(expireMillis).Time()
+	if expireDate.Before(now) {
+		expireDate = now
+	}
+	filter := bson.M{
+		"_id":           _id,
+		"isDeleted":     false,
+		"expireDate":    primitive.DateTime(expireMillis),
+		"status":        bson.M{"$in": bson.A{constants.JOB_PUBLISHED, constants.JOB_PAUSED, constants.JOB_EXPIRED}},
+		"closedByAdmin": bson.M{"$ne": true},
+	}
+	update := mongo.Pipeline{
+		{{"$set", bson.D{
+			{"expireDate", primitive.NewDateTimeFromTime(expireDate.Add(j.extendPeriod))},
+			// Expired jobs go live again, paused ones stay paused
+			{"status", bson.D{{"$cond", bson.A{
+				bson.D{{"$eq", bson.A{"$status", constants.JOB_EXPIRED}}},
+				constants.JOB_PUBLISHED,
+				"$status",
+			}}}},
+			{"isClosed", false},
+		}}},
+		{{"$unset", bson.A{"expiryNotifiedAt"}}},
+	}
+
+	var job models.Jobs
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = j.jobCollection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return models.Jobs{}, ErrInvalidExtendLink
+	}
+	if err != nil {
+		return models.Jobs{}, err
+	}
+	j.cache.Flush()
+	return job, nil
+}
+
+// PublishDueJobs publishes the scheduled jobs whose publish time has come
+func (j *JobRepository) PublishDueJobs(now time.Time) (int, error) {
+	cursor, err := j.jobCollection.Find(context.Background(), bson.M{
+		"isDeleted": false,
+		"status":    constants.JOB_SCHEDULED,
+		"publishAt": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var due []models.Jobs
+	if err := cursor.All(context.Background(), &due); err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, job := range due {
+		// Claim the job so observers are notified once, even with several instances
+		result, err := j.jobCollection.UpdateOne(
+			context.Background(),
+			bson.M{"_id": job.Id, "status": constants.JOB_SCHEDULED},
+			bson.M{
+				"$set":   bson.M{"status": constants.JOB_PUBLISHED, "isClosed": false, "publishedAt": primitive.NewDateTimeFromTime(now)},
+				"$unset": bson.M{"publishAt": ""},
+			},
+		)
+		if err != nil {
+			return published, err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		published++
+		job.Status = constants.JOB_PUBLISHED
+		j.notifier.Notify(&job)
+	}
+	if published > 0 {
+		j.cache.Flush()
+	}
+	return published, nil
+}
+
+// ExpireOverdueJobs expires the live and paused jobs past their expire date
+func (j *JobRepository) ExpireOverdueJobs(now time.Time) (int64, error) {
+	result, err := j.jobCollection.UpdateMany(
+		context.Background(),
+		bson.M{
+			"isDeleted":  false,
+			"status":     bson.M{"$in": bson.A{constants.JOB_PUBLISHED, constants.JOB_PAUSED}},
+			"expireDate": bson.M{"$lte": now},
+		},
+		bson.M{"$set": bson.M{"status": constants.JOB_EXPIRED, "isClosed": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if result.ModifiedCount > 0 {
+		j.cache.Flush()
+	}
+	return result.ModifiedCount, nil
+}
+
+// expiringJob is a published job about to expire with the company to remind
+type expiringJob struct {
+	models.Jobs  `bson:",inline"`
+	CompanyName  string `bson:"companyName"`
+	CompanyEmail string `bson:"companyEmail"`
+}
+
+// expiringJobs lists the published jobs expiring within the reminder window whose
+// company has not been reminded yet
+func (j *JobRepository) expiringJobs(now time.Time) ([]expiringJob, error) {
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{
+			{"isDeleted", false},
+			{"status", constants.JOB_PUBLISHED},
+			{"expireDate", bson.D{{"$gt", now}, {"$lte", now.Add(j.reminder)}}},
+			{"expiryNotifiedAt", bson.D{{"$exists", false}}},
+		}}},
+		{{"$lookup", bson.D{
+			{"from", "Company"},
+			{"localField", "companyID"},
+			{"foreignField", "_id"},
+			{"as", "company"},
+		}}},
+		{{"$unwind", "$company"}},
+		{{"$match", bson.D{{"company.isDeleted", false}}}},
+		{{"$set", bson.D{
+			{"companyName", "$company.companyName"},
+			{"companyEmail", "$company.contact.companyEmail"},
+		}}},
+		{{"$unset", bson.A{"company"}}},
+	}
+
+	cursor, err := j.jobCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []expiringJob
+	if err := cursor.All(context.Background(), &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// claimExpiryReminder marks a job as reminded, false when another run already did
+func (j *JobRepository) claimExpiryReminder(jobID primitive.ObjectID, now time.Time) (bool, error) {
+	result, err := j.jobCollection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": jobID, "expiryNotifiedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"expiryNotifiedAt": primitive.NewDateTimeFromTime(now)}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// jobMigrationsID is the Migration document recording how far the Job collection got
+const jobMigrationsID = "job"
+
+// migrationLease is how long an instance may hold the migrations before another one
+// takes over, in case it died halfway
+const migrationLease = 30 * time.Minute
+
+// jobMigration is a one-off pass over the Job collection
+type jobMigration struct {
+	version int
+	name    string
+	run     func(j *JobRepository) error
+}
+
+// jobMigrations run in order, each recorded as soon as it succeeds so it never runs
+// again. Append new ones with the next version, never renumber.
+var jobMigrations = []jobMigration{
+	{1, "status", (*JobRepository).backfillStatus},
+	{2, "search", (*JobRepository).backfillSearch},
+	{3, "application count", (*JobRepository).backfillApplicationCount},
+	{4, "geo points", (*JobRepository).backfillGeoPoints},
+	{5, "salary", (*JobRepository).backfillSalary},
+	{6, "work types", (*JobRepository).migrateWorkTypes},
+}
+
+// migrationState is the Migration document of the Job collection
+type migrationState struct {
+	Version int `bson:"version"`
+	// rate table the monthly salary figures were last computed with
+	SalaryRates string    `bson:"salaryRates"`
+	LockedUntil time.Time `bson:"lockedUntil"`
+}
+
+// ratesKey identifies a salary rate table, the order of the map does not matter
+func ratesKey(rates map[string]float64) string {
+	pairs := make([]string, 0, len(rates))
+	for currency, rate := range rates {
+		pairs = append(pairs, fmt.Sprintf("%s=%g", currency, rate))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// migrate brings the Job collection up to the latest migration. It runs in the
+// background so a cold start never waits on a scan of every job, and only in the
+// instance holding the lease, the others leave the work to it.
+func (j *JobRepository) migrate() {
+	j.ensureIndexes()
+	j.ensureViewIndexes()
+
+	ctx := context.Background()
+	state, ok := j.acquireMigrationLease(ctx)
+	if !ok {
+		return
+	}
+	defer j.releaseMigrationLease(ctx)
+
+	for _, migration := range jobMigrations {
+		if migration.version <= state.Version {
+			continue
+		}
+		if err := migration.run(j); err != nil {
+			log.Printf("Error running job migration %d (%s): %v", migration.version, migration.name, err)
+			return
+		}
+		if err := j.recordMigration(ctx, bson.M{"version": migration.version}); err != nil {
+			log.Printf("Error recording job migration %d (%s): %v", migration.version, migration.name, err)
+			return
+		}
+		log.Printf("Job migration %d (%s) done", migration.version, migration.name)
+	}
+
+	// Foreign salaries follow the rate table, recomputed whenever it changes
+	rates := ratesKey(j.salaryRates)
+	if rates == state.SalaryRates {
+		return
+	}
+	if err := j.renormalizeSalaries(); err != nil {
+		log.Printf("Error normalizing job salaries: %v", err)
+		return
+	}
+	if err := j.recordMigration(ctx, bson.M{"salaryRates": rates}); err != nil {
+		log.Printf("Error recording salary rates: %v", err)
+	}
+}
+
+// acquireMigrationLease takes the lease unless another instance holds it
+func (j *JobRepository) acquireMigrationLease(ctx context.Context) (migrationState, bool) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": jobMigrationsID,
+		"$or": bson.A{
+			bson.M{"lockedUntil": bson.M{"$exists": false}},
+			bson.M{"lockedUntil": bson.M{"$lte": now}},
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var state migrationState
+	err := j.migrationCollection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"lockedUntil": now.Add(migrationLease)}}, opts).Decode(&state)
+	// The document exists but did not match, it is locked
+	if mongo.IsDuplicateKeyError(err) {
+		return state, false
+	}
+	if err != nil {
+		log.Printf("Error acquiring job migration lease: %v", err)
+		return state, false
+	}
+	return state, true
+}
+
+func (j *JobRepository) recordMigration(ctx context.Context, fields bson.M) error {
+	_, err := j.migrationCollection.UpdateOne(ctx, bson.M{"_id": jobMigrationsID}, bson.M{"$set": fields})
+	return err
+}
+
+func (j *JobRepository) releaseMigrationLease(ctx context.Context) {
+	_, err := j.migrationCollection.UpdateOne(ctx, bson.M{"_id": jobMigrationsID}, bson.M{"$unset": bson.M{"lockedUntil": ""}})
+	if err != nil {
+		log.Printf("Error releasing job migration lease: %v", err)
+	}
+}
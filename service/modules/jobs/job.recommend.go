@@ -0,0 +1,337 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrCareerNotFound = errors.New("Không tìm thấy ứng viên")
+
+// Why a job is recommended, each reason lists the values that matched
+const (
+	ReasonSkills    = "SKILLS"
+	ReasonLanguages = "LANGUAGES"
+	ReasonCategory  = "CATEGORY"
+	ReasonTech      = "TECH"
+	ReasonLevel     = "LEVEL"
+	ReasonLocation  = "LOCATION"
+	// Careers without a profile or any history get the newest jobs
+	ReasonLatest = "LATEST"
+)
+
+// Weight of a single match of each reason in the score of a job. Skills and languages
+// come from the profile, the others from the jobs the career saved, applied to or viewed.
+var recommendWeights = map[string]int{
+	ReasonSkills:    3,
+	ReasonLanguages: 2,
+	ReasonCategory:  2,
+	ReasonTech:      1,
+	ReasonLevel:     1,
+	ReasonLocation:  1,
+}
+
+// maxInterests keeps the values a career met most often in its history
+const maxInterests = 10
+
+// careerInterests is what the recommendations of a career are based on
+type careerInterests struct {
+	skills     []string
+	languages  []string
+	categories []string
+	tech       []string
+	levels     []string
+	locations  []string
+	applied    []primitive.ObjectID
+}
+
+func (c careerInterests) empty() bool {
+	return len(c.skills)+len(c.languages)+len(c.categories)+len(c.tech)+len(c.levels)+len(c.locations) == 0
+}
+
+// lowerAll lowercases values and drops the empty ones, skills and tech are compared
+// case insensitively
+func lowerAll(values []string) []string {
+	lowered := []string{}
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			lowered = appendUnique(lowered, value)
+		}
+	}
+	return lowered
+}
+
+// topValues returns the maxInterests values counted most often
+func topValues(counts map[string]int) []string {
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(a, b int) bool {
+		if counts[values[a]] != counts[values[b]] {
+			return counts[values[a]] > counts[values[b]]
+		}
+		return values[a] < values[b]
+	})
+	if len(values) > maxInterests {
+		values = values[:maxInterests]
+	}
+	return values
+}
+
+// careerInterests reads the profile of a career and the jobs it saved, applied to and
+// viewed
+func (j *JobRepository) careerInterests(ctx context.Context, careerID primitive.ObjectID) (careerInterests, error) {
+	// Empty rather than nil, a nil slice is encoded as null which the set operators
+	// of recommendStages reject
+	interests := careerInterests{
+		skills:     []string{},
+		languages:  []string{},
+		categories: []string{},
+		tech:       []string{},
+		levels:     []string{},
+		locations:  []string{},
+	}
+
+	var career models.User
+	opts := options.FindOne().SetProjection(bson.M{"profile.skills": 1, "languages": 1})
+	err := j.careerCollection.FindOne(ctx, bson.M{"_id": careerID, "isDeleted": false}, opts).Decode(&career)
+	if err == mongo.ErrNoDocuments {
+		return interests, ErrCareerNotFound
+	}
+	if err != nil {
+		return interests, err
+	}
+	interests.skills = lowerAll(career.Profile.Skills)
+	for _, language := range career.Languages {
+		if language = utils.FoldText(language); language != "" {
+			interests.languages = appendUnique(interests.languages, language)
+		}
+	}
+
+	history := []primitive.ObjectID{}
+	var saved models.CareerSaveJob
+	if err := j.careerSaveCollection.FindOne(ctx, bson.M{"careerID": careerID}).Decode(&saved); err == nil {
+		history = append(history, saved.SaveJob...)
+	}
+	var viewed models.CareerViewedJob
+	if err := j.careerViewedCollection.FindOne(ctx, bson.M{"careerID": careerID}).Decode(&viewed); err == nil {
+		for _, view := range viewed.ViewedJob {
+			history = append(history, view.JobID)
+		}
+	}
+	applied, err := j.careerApplyCollection.Distinct(ctx, "jobID", bson.M{"careerID": careerID})
+	if err != nil {
+		return interests, err
+	}
+	for _, jobID := range applied {
+		if id, ok := jobID.(primitive.ObjectID); ok {
+			interests.applied = append(interests.applied, id)
+			history = append(history, id)
+		}
+	}
+	if len(history) == 0 {
+		return interests, nil
+	}
+
+	cursor, err := j.jobCollection.Find(ctx, bson.M{"_id": bson.M{"$in": history}}, options.Find().SetProjection(bson.M{
+		"jobCategory": 1, "jobTech": 1, "jobLevel": 1, "workingLocation": 1,
+	}))
+	if err != nil {
+		return interests, err
+	}
+	defer cursor.Close(ctx)
+	var jobs []models.Jobs
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return interests, err
+	}
+	categories, tech, levels, locations := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	for _, job := range jobs {
+		for _, category := range job.JobCategory {
+			categories[category]++
+		}
+		for _, value := range lowerAll(job.JobTech) {
+			tech[value]++
+		}
+		if job.JobLevel != "" {
+			levels[job.JobLevel]++
+		}
+		for _, location := range job.WorkingLocation {
+			locations[location]++
+		}
+	}
+	interests.categories = topValues(categories)
+	interests.tech = topValues(tech)
+	interests.levels = topValues(levels)
+	interests.locations = topValues(locations)
+	return interests, nil
+}
+
+// lowerArray lowercases the strings of an array field
+func lowerArray(field string) bson.D {
+	return bson.D{{"$map", bson.D{
+		{"input", bson.D{{"$ifNull", bson.A{field, bson.A{}}}}},
+		{"in", bson.D{{"$toLower", "$$this"}}},
+	}}}
+}
+
+// languageMatches lists the languages the folded description and requirements of a job
+// mention
+func languageMatches(languages []string) bson.D {
+	mentioned := bson.A{}
+	for _, language := range languages {
+		mentioned = append(mentioned, bson.D{{"$cond", bson.A{
+			bson.D{{"$regexMatch", bson.D{
+				{"input", bson.D{{"$ifNull", bson.A{"$search.body", ""}}}},
+				{"regex", `(^|[^a-z0-9])` + regexp.QuoteMeta(language) + `([^a-z0-9]|$)`},
+			}}},
+			bson.A{language},
+			bson.A{},
+		}}})
+	}
+	return bson.D{{"$concatArrays", mentioned}}
+}
+
+// recommendStages scores the jobs against the interests of a career and explains every
+// score by the reasons that contributed to it
+func recommendStages(interests careerInterests) []bson.D {
+	matches := bson.D{
+		{ReasonSkills, bson.D{{"$setIntersection", bson.A{
+			bson.D{{"$setUnion", bson.A{lowerArray("$jobTech"), lowerArray("$jobRequirement")}}},
+			interests.skills,
+		}}}},
+		{ReasonLanguages, languageMatches(interests.languages)},
+		{ReasonCategory, bson.D{{"$setIntersection", bson.A{bson.D{{"$ifNull", bson.A{"$jobCategory", bson.A{}}}}, interests.categories}}}},
+		{ReasonTech, bson.D{{"$setIntersection", bson.A{lowerArray("$jobTech"), interests.tech}}}},
+		{ReasonLevel, bson.D{{"$setIntersection", bson.A{bson.A{"$jobLevel"}, interests.levels}}}},
+		{ReasonLocation, bson.D{{"$setIntersection", bson.A{bson.D{{"$ifNull", bson.A{"$workingLocation", bson.A{}}}}, interests.locations}}}},
+	}
+
+	score := bson.A{}
+	reasons := bson.A{}
+	for _, match := range matches {
+		field := "$matches." + match.Key
+		score = append(score, bson.D{{"$multiply", bson.A{bson.D{{"$size", field}}, recommendWeights[match.Key]}}})
+		reasons = append(reasons, bson.D{{"$cond", bson.A{
+			bson.D{{"$gt", bson.A{bson.D{{"$size", field}}, 0}}},
+			bson.A{bson.D{{"reason", match.Key}, {"matches", field}}},
+			bson.A{},
+		}}})
+	}
+	return []bson.D{
+		{{"$addFields", bson.D{{"matches", matches}}}},
+		{{"$addFields", bson.D{
+			{"recommendScore", bson.D{{"$add", score}}},
+			{"reasons", bson.D{{"$concatArrays", reasons}}},
+		}}},
+		{{"$match", bson.D{{"recommendScore", bson.D{{"$gt", 0}}}}}},
+	}
+}
+
+// RecommendJobs pages through the open jobs a career has not applied to, the best match
+// with its profile and history first
+func (j *JobRepository) RecommendJobs(careerID string, page int, pageSize int) (bson.M, error) {
+	careerObjID, err := primitive.ObjectIDFromHex(careerID)
+	if err != nil {
+		return nil, ErrCareerNotFound
+	}
+	page, pageSize = pageBounds(page, pageSize)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	interests, err := j.careerInterests(ctx, careerObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	matchStage := publicJobFilter()
+	if len(interests.applied) > 0 {
+		matchStage["_id"] = bson.M{"$nin": interests.applied}
+	}
+	pipeline := mongo.Pipeline{{{"$match", matchStage}}}
+	if interests.empty() {
+		pipeline = append(pipeline, bson.D{{"$addFields", bson.D{
+			{"recommendScore", 0},
+			{"reasons", bson.A{bson.D{{"reason", ReasonLatest}, {"matches", bson.A{}}}}},
+		}}})
+	} else {
+		pipeline = append(pipeline, recommendStages(interests)...)
+	}
+	pipeline = append(pipeline,
+		bson.D{{"$sort", bson.D{{"recommendScore", -1}, {"createAt", -1}, {"_id", -1}}}},
+		bson.D{{"$facet", bson.D{
+			{"totalCount", bson.A{bson.D{{"$count", "count"}}}},
+			{"data", bson.A{
+				bson.D{{"$skip", int64((page - 1) * pageSize)}},
+				bson.D{{"$limit", int64(pageSize)}},
+				bson.D{{"$lookup", bson.D{
+					{"from", "Company"},
+					{"localField", "companyID"},
+					{"foreignField", "_id"},
+					{"as", "company"},
+				}}},
+				bson.D{{"$project", bson.D{
+					{"_id", 1},
+					{"jobTitle", 1},
+					{"companyID", 1},
+					{"companyName", bson.D{{"$first", "$company.companyName"}}},
+					{"companyImage", bson.D{{"$first", "$company.companyImage"}}},
+					{"jobSalaryMin", publicSalary("$jobSalaryMin", "$salary.hidden")},
+					{"jobSalaryMax", publicSalary("$jobSalaryMax", "$salary.hidden")},
+					{"salary", 1},
+					{"jobCategory", 1},
+					{"jobTech", 1},
+					{"jobLevel", 1},
+					{"workingLocation", 1},
+					{"workArrangement", 1},
+					{"employmentType", 1},
+					{"createAt", 1},
+					{"expireDate", 1},
+					{"recommendScore", 1},
+					{"reasons", 1},
+				}}},
+			}},
+		}}},
+	)
+
+	cursor, err := j.jobCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var result []struct {
+		TotalCount []struct {
+			Count int64 `bson:"count"`
+		} `bson:"totalCount"`
+		Data []bson.M `bson:"data"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	totalDocs := int64(0)
+	docs := []bson.M{}
+	if len(result) > 0 {
+		if len(result[0].TotalCount) > 0 {
+			totalDocs = result[0].TotalCount[0].Count
+		}
+		docs = append(docs, result[0].Data...)
+	}
+	return bson.M{
+		"docs":        docs,
+		"totalDocs":   totalDocs,
+		"currentPage": page,
+		"totalPage":   int64(math.Ceil(float64(totalDocs) / float64(pageSize))),
+	}, nil
+}
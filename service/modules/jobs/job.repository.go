@@ -2,13 +2,19 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hireforwork-server/config"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/interfaces"
 	"hireforwork-server/models"
+	"hireforwork-server/service/modules/geo"
 	"hireforwork-server/service/observe"
 	"log"
 	"math"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -18,12 +24,31 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrJobNotFound is also returned for jobs of another company so ownership is not leaked
+var ErrJobNotFound = errors.New("Không tìm thấy bài đăng")
+
+var ErrJobClosedByAdmin = errors.New("Bài đăng đã bị quản trị viên đóng, vui lòng liên hệ quản trị viên")
+
+var jobMigrationOnce sync.Once
+
 type JobRepository struct {
-	jobCollection         *mongo.Collection
-	careerSaveCollection  *mongo.Collection
-	careerApplyCollection *mongo.Collection
-	cache                 *cache.Cache
-	notifier              *observe.JobEventManager
+	jobCollection          *mongo.Collection
+	careerSaveCollection   *mongo.Collection
+	careerApplyCollection  *mongo.Collection
+	companyCollection      *mongo.Collection
+	jobViewCollection      *mongo.Collection
+	careerViewedCollection *mongo.Collection
+	careerCollection       *mongo.Collection
+	migrationCollection    *mongo.Collection
+	cache                  *cache.Cache
+	notifier               *observe.JobEventManager
+	secret                 []byte
+	baseURL                string
+	reminder               time.Duration
+	extendPeriod           time.Duration
+	geocoder               geo.Geocoder
+	salaryRates            map[string]float64
+	viewWindow             time.Duration
 }
 
 /*
@@ -35,6 +60,11 @@ func NewJobRepository(dbInstance *db.DB) *JobRepository {
 	jobCollection := dbInstance.GetCollection("Job")
 	careerSaveCollection := dbInstance.GetCollection("CareerSaveJob")
 	careerApplyCollection := dbInstance.GetCollection("CareerApplyJob")
+	companyCollection := dbInstance.GetCollection("Company")
+	jobViewCollection := dbInstance.GetCollection("JobView")
+	careerViewedCollection := dbInstance.GetCollection("CareerViewedJob")
+	careerCollection := dbInstance.GetCollection("Career")
+	migrationCollection := dbInstance.GetCollection("Migration")
 	// Tạo cache với defaultExpiration là 5 phút và cleanupInterval là 10 phút
 	jobCache := cache.New(5*time.Minute, 10*time.Minute)
 	// Create the event manager
@@ -45,49 +75,83 @@ func NewJobRepository(dbInstance *db.DB) *JobRepository {
 
 	// Register the observer
 	notifier.Register(skillMatcher)
-	return &JobRepository{
-		jobCollection:         jobCollection,
-		careerSaveCollection:  careerSaveCollection,
-		careerApplyCollection: careerApplyCollection,
-		cache:                 jobCache,
-		notifier:              notifier,
-	}
+
+	cfg := config.GetInstance()
+	repository := &JobRepository{
+		jobCollection:          jobCollection,
+		careerSaveCollection:   careerSaveCollection,
+		careerApplyCollection:  careerApplyCollection,
+		companyCollection:      companyCollection,
+		jobViewCollection:      jobViewCollection,
+		careerViewedCollection: careerViewedCollection,
+		careerCollection:       careerCollection,
+		migrationCollection:    migrationCollection,
+		cache:                  jobCache,
+		notifier:               notifier,
+		secret:                 []byte(cfg.SecretKey),
+		baseURL:                cfg.AppBaseURL,
+		reminder:               cfg.JobExpiryReminder,
+		extendPeriod:           cfg.JobExtendPeriod,
+		geocoder:               geo.NewGazetteer(),
+		salaryRates:            cfg.SalaryRates,
+		viewWindow:             cfg.JobViewWindow,
+	}
+	jobMigrationOnce.Do(func() {
+		go repository.migrate()
+	})
+	return repository
 }
 
+// GetJob lists the public jobs page by page, or after filter.Cursor when it is set
 func (j *JobRepository) GetJob(page, pageSize int, filter interfaces.IJobFilter) (bson.M, error) {
-	skip := (page - 1) * pageSize
-	matchStage := bson.M{"isDeleted": false, "isClosed": false}
-
-	facetStage := bson.D{
-		{"$facet", bson.D{
-			{"totalCount", []bson.D{{{"$count", "count"}}}},
-			{"data", []bson.D{
-				{{"$skip", int64(skip)}},
-				{{"$limit", int64(pageSize)}},
-				{{"$addFields", bson.D{
-					{"companyName", "$companyDetails.companyName"},
-					{"companyImage", "$companyDetails.companyImage"},
-				}}},
-			}},
-		}},
+	page, pageSize = pageBounds(page, pageSize)
+	filter, err := normalizeSalaryFilter(filter, j.salaryRates)
+	if err != nil {
+		return nil, err
+	}
+	if filter, err = normalizeWorkTypeFilter(filter); err != nil {
+		return nil, err
 	}
+	matchStage := publicJobFilter()
 
-	matchOption := bson.M{}
+	// Full text search over title, tech, company name and description
+	textQuery := textSearchQuery(filter.Query)
+	if textQuery != "" {
+		matchStage["$text"] = bson.M{"$search": textQuery}
+	}
 
-	if filter.IsExpire {
-		currentDate := time.Now()
-		matchOption["expireDate"] = bson.M{"$gt": currentDate}
+	// Jobs within radiusKm of a place or a "lat,lng" point
+	center, within, err := j.nearFilter(filter.Near, filter.RadiusKm)
+	if err != nil {
+		return nil, err
+	}
+	if within != nil {
+		matchStage["geoPoints"] = within
 	}
 
-	if filter.Query != "" {
-		matchStage["$or"] = bson.A{
-			bson.M{"jobTitle": bson.M{"$regex": filter.Query, "$options": "i"}},
-			bson.M{"jobRequirement": bson.M{"$elemMatch": bson.M{"$regex": filter.Query, "$options": "i"}}},
+	sortBy, err := resolveJobSort(filter.Sort, textQuery != "", center != nil)
+	if err != nil {
+		return nil, err
+	}
+	pageStages := []bson.D{
+		{{"$skip", int64((page - 1) * pageSize)}},
+		{{"$limit", int64(pageSize)}},
+	}
+	if filter.Cursor != "" {
+		afterCursor, err := cursorMatch(filter.Cursor, sortBy)
+		if err != nil {
+			return nil, err
 		}
+		pageStages = []bson.D{afterCursor, {{"$limit", int64(pageSize)}}}
 	}
 
+	// Sidebar filters are applied inside the facet stage, each facet ignores its own
+	facetStage := jobFacetStage(facetFilters(filter), pageStages)
+
+	matchOption := bson.M{}
+
 	if filter.JobTitle != "" {
-		matchStage["jobTitle"] = bson.M{"$regex": filter.JobTitle, "$options": "i"}
+		matchStage["jobTitle"] = bson.M{"$regex": regexp.QuoteMeta(filter.JobTitle), "$options": "i"}
 	}
 	//filter by create date
 	if filter.DateCreateFrom != "" && filter.DateCreateTo != "" {
@@ -103,44 +167,34 @@ func (j *JobRepository) GetJob(page, pageSize int, filter interfaces.IJobFilter)
 			"$lte": filter.EndDateTo,
 		}
 	}
-	//filter by salary
-	if filter.SalaryFrom != 0 && filter.SalaryTo != 0 {
-		matchOption["$and"] = []bson.M{
-			{"jobSalaryMin": bson.M{"$gte": filter.SalaryFrom}},
-			{"jobSalaryMax": bson.M{"$lte": filter.SalaryTo}},
-		}
-	}
-	//filter by category
-	if len(filter.JobCategory) > 0 {
-		matchOption["jobCategory"] = bson.M{"$all": filter.JobCategory}
-	}
-	//filter by working location
-	if len(filter.WorkingLocation) > 0 {
-		matchOption["workingLocation"] = bson.M{"$in": filter.WorkingLocation}
-	}
-
 	//filter by job require
 	if len(filter.JobRequirement) > 0 {
 		matchOption["jobRequirement"] = bson.M{"$in": filter.JobRequirement}
 	}
+	//filter by remote country
+	if filter.RemoteCountry != "" {
+		for key, value := range remoteFrom(filter.RemoteCountry) {
+			matchOption[key] = value
+		}
+	}
 	//filter by hot
 	if filter.IsHot {
 		matchOption["isHot"] = filter.IsHot
 	}
-	//filter by job level
-	if filter.JobLevel != "" {
-		matchOption["jobLevel"] = filter.JobLevel
-	}
-
 	projectStage := bson.D{
 		{"$project", bson.D{
 			{"totalCount", 1},
+			{"facets", facetProjection()},
+			{"lastKey", bson.D{{"$arrayElemAt", bson.A{"$data.sortKey", -1}}}},
+			{"lastID", bson.D{{"$arrayElemAt", bson.A{"$data._id", -1}}}},
 			{"data", bson.D{
 				{"$map", bson.D{
 					{"input", "$data"},
 					{"as", "doc"},
 					{"in", bson.D{
 						{"_id", "$$doc._id"},
+						{"score", "$$doc.score"},
+						{"distanceKm", "$$doc.distanceKm"},
 						{"companyID", "$$doc.companyID"},
 						{"companyName", "$$doc.companyName"},
 						{"companyImage", "$$doc.companyImage"},
@@ -151,11 +205,14 @@ func (j *JobRepository) GetJob(page, pageSize int, filter interfaces.IJobFilter)
 						{"jobDescription", "$$doc.jobDescription"},
 						{"jobLevel", "$$doc.jobLevel"},
 						{"jobRequirement", "$$doc.jobRequirement"},
-						{"jobSalaryMax", "$$doc.jobSalaryMax"},
-						{"jobSalaryMin", "$$doc.jobSalaryMin"},
+						{"jobSalaryMax", publicSalary("$$doc.jobSalaryMax", "$$doc.salary.hidden")},
+						{"jobSalaryMin", publicSalary("$$doc.jobSalaryMin", "$$doc.salary.hidden")},
+						{"salary", "$$doc.salary"},
 						{"jobTitle", "$$doc.jobTitle"},
 						{"quantity", "$$doc.quantity"},
 						{"workingLocation", "$$doc.workingLocation"},
+						{"workArrangement", "$$doc.workArrangement"},
+						{"employmentType", "$$doc.employmentType"},
 					}},
 				}},
 			}},
@@ -163,8 +220,10 @@ func (j *JobRepository) GetJob(page, pageSize int, filter interfaces.IJobFilter)
 	}
 
 	//default pipeline
-	pipeline := mongo.Pipeline{
-		{{"$match", matchStage}},
+	pipeline := mongo.Pipeline{{{"$match", matchStage}}}
+	// Search results carry their relevance score and distance
+	pipeline = append(pipeline, sortStages(sortBy, textQuery != "", center)...)
+	pipeline = append(pipeline, mongo.Pipeline{
 		{{"$lookup", bson.D{
 			{"from", "Company"},
 			{"localField", "companyID"},
@@ -182,13 +241,13 @@ func (j *JobRepository) GetJob(page, pageSize int, filter interfaces.IJobFilter)
 			}},
 		}}},
 		{{"$match", matchOption}},
-	}
+	}...)
 
 	if filter.CompanyName != "" {
 		pipeline = append(pipeline, bson.D{{
 			"$match", bson.D{{
 				"companyDetails.companyName", bson.D{
-					{"$regex", filter.CompanyName},
+					{"$regex", regexp.QuoteMeta(filter.CompanyName)},
 					{"$options", "i"},
 				},
 			}},
@@ -219,71 +278,207 @@ func (j *JobRepository) GetJob(page, pageSize int, filter interfaces.IJobFilter)
 	jobs := result[0]["data"].(bson.A)
 	totalPage := int64(math.Ceil(float64(totalDocs) / float64(pageSize)))
 
+	// A full page may have a next one, its cursor works for offset pages as well
+	nextCursor := ""
+	if lastID, ok := result[0]["lastID"].(primitive.ObjectID); ok && len(jobs) == pageSize {
+		if nextCursor, err = encodeCursor(sortBy, result[0]["lastKey"], lastID); err != nil {
+			return nil, err
+		}
+	}
+
 	return bson.M{
 		"docs":        jobs,
 		"totalDocs":   totalDocs,
 		"currentPage": page,
 		"totalPage":   totalPage,
+		"sort":        sortBy,
+		"nextCursor":  nextCursor,
+		"facets":      result[0]["facets"],
 	}, nil
 }
 
-func (j *JobRepository) CreateJob(job models.Jobs) (models.Jobs, error) {
+// ownedJobFilter matches a live job, restricted to companyID unless it is empty (admin)
+func ownedJobFilter(jobID primitive.ObjectID, companyID string) (bson.M, error) {
+	filter := bson.M{"_id": jobID, "isDeleted": false}
+	if companyID != "" {
+		companyObjID, err := primitive.ObjectIDFromHex(companyID)
+		if err != nil {
+			return nil, ErrJobNotFound
+		}
+		filter["companyID"] = companyObjID
+	}
+	return filter, nil
+}
+
+// CreateJob posts a job for companyID, taken from the token rather than the body.
+// An empty companyID (admin) keeps the companyID of the payload.
+func (j *JobRepository) CreateJob(companyID string, job models.Jobs) (models.Jobs, error) {
+	if companyID != "" {
+		companyObjID, err := primitive.ObjectIDFromHex(companyID)
+		if err != nil {
+			return models.Jobs{}, ErrJobNotFound
+		}
+		job.CompanyID = companyObjID
+	}
+	if job.CompanyID.IsZero() {
+		return models.Jobs{}, fmt.Errorf("Thiếu thông tin công ty")
+	}
+
 	currentTime := time.Now()
+	if err := prepareNewJob(&job, currentTime); err != nil {
+		return models.Jobs{}, err
+	}
+	if err := normalizeSalary(&job, j.salaryRates); err != nil {
+		return models.Jobs{}, err
+	}
+	if err := normalizeWorkTypes(&job); err != nil {
+		return models.Jobs{}, err
+	}
 	job.Id = primitive.NewObjectID()
 	job.CreateAt = primitive.NewDateTimeFromTime(currentTime)
-	job.IsClosed = false
+	job.IsDeleted = false
+	companyName, companyLocation := j.companyDetails(job.CompanyID)
+	job.Search = jobSearch(job, companyName)
+	job.GeoPoints = j.geoPoints(job.WorkingLocation, companyLocation)
 	result, err := j.jobCollection.InsertOne(context.Background(), job)
-	fmt.Println(err)
 	if err != nil {
+		log.Printf("Error creating job: %v", err)
 		return models.Jobs{}, fmt.Errorf("Đã có lỗi xảy ra khi tạo bài đăng")
 	}
 	job.Id = result.InsertedID.(primitive.ObjectID)
-	//use notify in observe pattern whenever a job created
-	fmt.Println("Job created, notifying observers...")
-	j.notifier.Notify(&job)
-	fmt.Println("Observers notified")
+	//use notify in observe pattern whenever a job goes live, drafts and scheduled jobs notify once published
+	if job.Status == constants.JOB_PUBLISHED {
+		j.notifier.Notify(&job)
+	}
 	return job, nil
 }
 
-func (j *JobRepository) UpdateJob(job models.Jobs) (models.Jobs, error) {
-	filter := bson.M{"_id": job.Id}
+// UpdateJob edits the content of a job, its status only changes through ChangeStatus
+func (j *JobRepository) UpdateJob(companyID string, job models.Jobs) (models.Jobs, error) {
+	filter, err := ownedJobFilter(job.Id, companyID)
+	if err != nil {
+		return models.Jobs{}, err
+	}
+
+	if err := normalizeSalary(&job, j.salaryRates); err != nil {
+		return models.Jobs{}, err
+	}
+	if err := normalizeWorkTypes(&job); err != nil {
+		return models.Jobs{}, err
+	}
+
+	var current models.Jobs
+	currentOpts := options.FindOne().SetProjection(bson.M{"status": 1, "publishAt": 1, "expireDate": 1})
+	if err := j.jobCollection.FindOne(context.Background(), filter, currentOpts).Decode(&current); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return models.Jobs{}, ErrJobNotFound
+		}
+		log.Printf("Error updating job %s: %v", job.Id.Hex(), err)
+		return models.Jobs{}, fmt.Errorf("Có lỗi xảy ra khi cập nhập lại thông tin")
+	}
+	expiryChanged := job.ExpireDate != current.ExpireDate
+	if expiryChanged {
+		if err := validateSchedule(current.Status, current.PublishAt.Time(), job.ExpireDate.Time(), time.Now()); err != nil {
+			return models.Jobs{}, err
+		}
+	}
 
+	search := jobSearch(job, "")
 	update := bson.M{
 		"$set": bson.M{
+			"search.title":     search.Title,
+			"search.body":      search.Body,
 			"jobTitle":         job.JobTitle,
 			"jobSalaryMin":     job.JobSalaryMin,
 			"jobSalaryMax":     job.JobSalaryMax,
+			"salary":           job.Salary,
 			"jobRequirement":   job.JobRequirement,
 			"workingLocation":  job.WorkingLocation,
 			"isHot":            job.IsHot,
-			"isClosed":         job.IsClosed,
-			"isDeleted":        job.IsDeleted,
 			"expireDate":       job.ExpireDate,
 			"jobCategory":      job.JobCategory,
 			"jobDescription":   job.JobDescription,
 			"jobLevel":         job.JobLevel,
 			"recruitmentCount": job.RecruitmentCount,
-			"workingType":      job.WorkType,
+			"workArrangement":  job.WorkArrangement,
+			"employmentType":   job.EmploymentType,
 		},
 	}
+	if expiryChanged {
+		// A new expiry gets its own reminder
+		update["$unset"] = bson.M{"expiryNotifiedAt": ""}
+	}
+	// Only an admin can touch a job a moderator closed
+	if companyID != "" {
+		filter["closedByAdmin"] = bson.M{"$ne": true}
+	}
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	err := j.jobCollection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&job)
+	err = j.jobCollection.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		delete(filter, "closedByAdmin")
+		if count, _ := j.jobCollection.CountDocuments(context.Background(), filter); count > 0 {
+			return models.Jobs{}, ErrJobClosedByAdmin
+		}
+		return models.Jobs{}, ErrJobNotFound
+	}
 	if err != nil {
-		fmt.Println(err)
+		log.Printf("Error updating job %s: %v", job.Id.Hex(), err)
 		return models.Jobs{}, fmt.Errorf("Có lỗi xảy ra khi cập nhập lại thông tin")
 	}
+	j.refreshGeoPoints(&job)
+	j.cache.Flush()
 	return job, nil
 }
 
+// DeleteJob soft deletes a job owned by companyID (any job when companyID is empty)
+func (j *JobRepository) DeleteJob(companyID string, jobID string) error {
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return ErrJobNotFound
+	}
+
+	filter, err := ownedJobFilter(_id, companyID)
+	if err != nil {
+		return err
+	}
+
+	result, err := j.jobCollection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"isDeleted": true}})
+	if err != nil {
+		return fmt.Errorf("Có lỗi xảy ra khi xóa bài đăng")
+	}
+	if result.MatchedCount == 0 {
+		return ErrJobNotFound
+	}
+	j.cache.Flush()
+	return nil
+}
+
+// ForceCloseJob closes any live job for moderation, the company cannot reopen it
+func (j *JobRepository) ForceCloseJob(jobID string, reason string) error {
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return ErrJobNotFound
+	}
+
+	result, err := j.jobCollection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": _id, "isDeleted": false},
+		bson.M{"$set": bson.M{"status": constants.JOB_CLOSED, "isClosed": true, "closedByAdmin": true, "closeReason": reason}},
+	)
+	if err != nil {
+		return fmt.Errorf("Có lỗi xảy ra khi đóng bài đăng")
+	}
+	if result.MatchedCount == 0 {
+		return ErrJobNotFound
+	}
+	j.cache.Flush()
+	return nil
+}
+
 func (j *JobRepository) GetLatestJobs() ([]models.Jobs, error) {
 	var jobs []models.Jobs
 
-	filter := bson.M{
-		"isDeleted": false,
-		"expireDate": bson.M{
-			"$gt": time.Now(),
-		},
-	}
+	filter := publicJobFilter()
 	opts := options.Find().SetSort(bson.D{{"createAt", -1}}).SetLimit(10)
 
 	cursor, err := j.jobCollection.Find(context.Background(), filter, opts)
@@ -295,6 +490,9 @@ func (j *JobRepository) GetLatestJobs() ([]models.Jobs, error) {
 	if err := cursor.All(context.Background(), &jobs); err != nil {
 		return nil, err
 	}
+	for i := range jobs {
+		hideSalary(&jobs[i])
+	}
 
 	return jobs, nil
 }
@@ -343,7 +541,12 @@ func (j *JobRepository) GetJobByID(jobID string, userId string) (bson.M, error)
 
 func (j *JobRepository) buildJobPipeline(jobID primitive.ObjectID, userId string) mongo.Pipeline {
 	pipeline := mongo.Pipeline{
-		{{"$match", bson.D{{"_id", jobID}}}},
+		// Drafts, scheduled and archived jobs are not public yet or anymore
+		{{"$match", bson.D{
+			{"_id", jobID},
+			{"isDeleted", false},
+			{"status", bson.D{{"$nin", bson.A{constants.JOB_DRAFT, constants.JOB_SCHEDULED, constants.JOB_ARCHIVED}}}},
+		}}},
 		{{"$lookup", bson.D{
 			{"from", "Company"},
 			{"localField", "companyID"},
@@ -358,8 +561,8 @@ func (j *JobRepository) buildJobPipeline(jobID primitive.ObjectID, userId string
 			{"employeeSize", "$company.employeeSize"},
 			{"_id", 1},
 			{"jobTitle", 1},
-			{"jobSalaryMin", "$company.jobSalaryMin"},
-			{"jobSalaryMax", "$company.jobSalaryMax"},
+			{"jobSalaryMin", publicSalary("$jobSalaryMin", "$salary.hidden")},
+			{"jobSalaryMax", publicSalary("$jobSalaryMax", "$salary.hidden")},
 			{"jobRequirement", "$company.jobRequirement"},
 			{"jobCategory", "$company.jobCategory"},
 			{"jobDescription", "$company.jobDescription"},
@@ -502,14 +705,29 @@ func (j *JobRepository) SaveJob(careerID string, jobID string) (bson.M, error) {
 
 func (j *JobRepository) Apply(request interfaces.IJobApply) error {
 	careerObjID, _ := primitive.ObjectIDFromHex(request.IDCareer)
-	companyObjID, _ := primitive.ObjectIDFromHex(request.CompanyID)
 	jobObjID, _ := primitive.ObjectIDFromHex(request.JobID)
 
+	// The company comes from the job, not the request, it decides who may review the
+	// application
+	var job struct {
+		CompanyID primitive.ObjectID `bson:"companyID"`
+	}
+	openFilter := publicJobFilter()
+	openFilter["_id"] = jobObjID
+	opts := options.FindOne().SetProjection(bson.M{"companyID": 1})
+	err := j.jobCollection.FindOne(context.Background(), openFilter, opts).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return ErrJobNotOpen
+	}
+	if err != nil {
+		return err
+	}
+
 	application := models.CareerApplyJob{
 		ID:        primitive.NewObjectID(),
 		CareerID:  careerObjID,
 		JobID:     jobObjID,
-		CompanyID: companyObjID,
+		CompanyID: job.CompanyID,
 		CareerCV:  request.CareerCV,
 		CreateAt:  primitive.NewDateTimeFromTime(time.Now()),
 		IsDeleted: false,
@@ -523,10 +741,17 @@ func (j *JobRepository) Apply(request interfaces.IJobApply) error {
 	}
 
 	var existingDoc bson.M
-	err := j.careerApplyCollection.FindOne(context.Background(), filter).Decode(&existingDoc)
+	err = j.careerApplyCollection.FindOne(context.Background(), filter).Decode(&existingDoc)
 
 	if err == mongo.ErrNoDocuments {
-		j.careerApplyCollection.InsertOne(context.Background(), application)
+		if _, err := j.careerApplyCollection.InsertOne(context.Background(), application); err != nil {
+			return err
+		}
+		// Popularity sorts by the number of applications
+		_, err = j.jobCollection.UpdateOne(context.Background(), bson.M{"_id": jobObjID}, bson.M{"$inc": bson.M{"applicationCount": 1}})
+		if err != nil {
+			log.Printf("Error counting application for job %s: %v", jobObjID.Hex(), err)
+		}
 		return nil
 	}
 	return fmt.Errorf("Job already applied")
@@ -0,0 +1,202 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireforwork-server/constants"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/models"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrInvalidSalary = errors.New("Mức lương không hợp lệ")
+
+// hoursPerMonth turns hourly salaries into monthly ones, 22 working days of 8 hours
+const hoursPerMonth = 176
+
+// monthlySalary is the top of the monthly VND range of a job, the figure salary facets
+// and the salary sort go by
+var monthlySalary = bson.D{{"$ifNull", bson.A{
+	bson.D{{"$max", bson.A{"$salary.monthlyMin", "$salary.monthlyMax"}}},
+	0,
+}}}
+
+// monthlyAmount converts amount paid per period in a currency worth rate VND
+func monthlyAmount(amount int64, rate float64, period string) int64 {
+	monthly := float64(amount) * rate
+	switch period {
+	case constants.SALARY_HOURLY:
+		monthly *= hoursPerMonth
+	case constants.SALARY_YEARLY:
+		monthly /= 12
+	}
+	return int64(math.Round(monthly))
+}
+
+// normalizeSalary defaults the currency, period and basis of a job salary, validates its
+// range and computes the monthly VND figures. A bound left at 0 is open, a salary with
+// neither bound is negotiable. Hidden salaries get no figures so they cannot be found by
+// filtering or sorting on them.
+func normalizeSalary(job *models.Jobs, rates map[string]float64) error {
+	salary := &job.Salary
+	if salary.Currency == "" {
+		salary.Currency = constants.SALARY_VND
+	}
+	if salary.Period == "" {
+		salary.Period = constants.SALARY_MONTHLY
+	}
+	if salary.Basis == "" {
+		salary.Basis = constants.SALARY_GROSS
+	}
+
+	rate, ok := rates[salary.Currency]
+	switch {
+	case !ok:
+		return ErrInvalidSalary
+	case salary.Period != constants.SALARY_HOURLY && salary.Period != constants.SALARY_MONTHLY && salary.Period != constants.SALARY_YEARLY:
+		return ErrInvalidSalary
+	case salary.Basis != constants.SALARY_GROSS && salary.Basis != constants.SALARY_NET:
+		return ErrInvalidSalary
+	case job.JobSalaryMin < 0 || job.JobSalaryMax < 0:
+		return ErrInvalidSalary
+	case job.JobSalaryMax > 0 && job.JobSalaryMin > job.JobSalaryMax:
+		return ErrInvalidSalary
+	}
+
+	if job.JobSalaryMin == 0 && job.JobSalaryMax == 0 {
+		salary.Negotiable = true
+	}
+	salary.MonthlyMin, salary.MonthlyMax = 0, 0
+	if !salary.Hidden {
+		salary.MonthlyMin = monthlyAmount(job.JobSalaryMin, rate, salary.Period)
+		salary.MonthlyMax = monthlyAmount(job.JobSalaryMax, rate, salary.Period)
+	}
+	return nil
+}
+
+// normalizeSalaryFilter converts the salary bounds of a search to monthly VND like the
+// figures they are compared with
+func normalizeSalaryFilter(filter interfaces.IJobFilter, rates map[string]float64) (interfaces.IJobFilter, error) {
+	if filter.SalaryCurrency == "" {
+		filter.SalaryCurrency = constants.SALARY_VND
+	}
+	if filter.SalaryPeriod == "" {
+		filter.SalaryPeriod = constants.SALARY_MONTHLY
+	}
+	bounds := models.Jobs{
+		JobSalaryMin: filter.SalaryFrom,
+		JobSalaryMax: filter.SalaryTo,
+		Salary:       models.JobSalary{Currency: filter.SalaryCurrency, Period: filter.SalaryPeriod},
+	}
+	if err := normalizeSalary(&bounds, rates); err != nil {
+		return filter, err
+	}
+	filter.SalaryFrom, filter.SalaryTo = bounds.Salary.MonthlyMin, bounds.Salary.MonthlyMax
+	filter.SalaryCurrency, filter.SalaryPeriod = constants.SALARY_VND, constants.SALARY_MONTHLY
+	return filter, nil
+}
+
+// salaryOverlap matches the jobs whose monthly range overlaps [from, to], either bound
+// may be 0 for an open range. Jobs without figures never match.
+func salaryOverlap(from int64, to int64) bson.M {
+	clauses := bson.A{bson.M{"$or": bson.A{
+		bson.M{"salary.monthlyMin": bson.M{"$gt": 0}},
+		bson.M{"salary.monthlyMax": bson.M{"$gt": 0}},
+	}}}
+	if to > 0 {
+		clauses = append(clauses, bson.M{"salary.monthlyMin": bson.M{"$lte": to}})
+	}
+	if from > 0 {
+		clauses = append(clauses, bson.M{"$or": bson.A{
+			bson.M{"salary.monthlyMax": bson.M{"$gte": from}},
+			bson.M{"salary.monthlyMax": 0},
+		}})
+	}
+	return bson.M{"$and": clauses}
+}
+
+// publicSalary drops an amount of a job whose company chose to hide its salary
+func publicSalary(amount string, hidden string) bson.D {
+	return bson.D{{"$cond", bson.A{hidden, "$$REMOVE", amount}}}
+}
+
+// hideSalary clears the amounts of a hidden salary before a job is shown publicly
+func hideSalary(job *models.Jobs) {
+	if job.Salary.Hidden {
+		job.JobSalaryMin, job.JobSalaryMax = 0, 0
+	}
+}
+
+// backfillSalary describes the salary of jobs saved before salaries were structured, they
+// were all gross monthly amounts in VND
+func (j *JobRepository) backfillSalary() error {
+	update := mongo.Pipeline{{{"$set", bson.D{{"salary", bson.D{
+		{"currency", constants.SALARY_VND},
+		{"period", constants.SALARY_MONTHLY},
+		{"basis", constants.SALARY_GROSS},
+		{"negotiable", bson.D{{"$and", bson.A{
+			bson.D{{"$lte", bson.A{bson.D{{"$ifNull", bson.A{"$jobSalaryMin", 0}}}, 0}}},
+			bson.D{{"$lte", bson.A{bson.D{{"$ifNull", bson.A{"$jobSalaryMax", 0}}}, 0}}},
+		}}}},
+		{"hidden", false},
+		{"monthlyMin", bson.D{{"$ifNull", bson.A{"$jobSalaryMin", 0}}}},
+		{"monthlyMax", bson.D{{"$ifNull", bson.A{"$jobSalaryMax", 0}}}},
+	}}}}}}
+	_, err := j.jobCollection.UpdateMany(context.Background(), bson.M{"salary": bson.M{"$exists": false}}, update)
+	return err
+}
+
+// renormalizeSalaries recomputes the monthly figures of salaries in a foreign currency,
+// the rate table may have changed since they were posted
+func (j *JobRepository) renormalizeSalaries() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	filter := bson.M{"salary.currency": bson.M{"$ne": constants.SALARY_VND}, "salary.hidden": false}
+	opts := options.Find().SetProjection(bson.M{"jobSalaryMin": 1, "jobSalaryMax": 1, "salary": 1})
+	cursor, err := j.jobCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("reading job salaries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	writes := []mongo.WriteModel{}
+	var failed error
+	flush := func() {
+		if len(writes) == 0 {
+			return
+		}
+		if _, err := j.jobCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			failed = fmt.Errorf("normalizing job salaries: %w", err)
+		}
+		writes = writes[:0]
+	}
+	for cursor.Next(ctx) {
+		var job models.Jobs
+		if err := cursor.Decode(&job); err != nil {
+			continue
+		}
+		before := job.Salary
+		// A currency dropped from the rate table keeps its last figures
+		if err := normalizeSalary(&job, j.salaryRates); err != nil || job.Salary == before {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": job.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"salary.monthlyMin": job.Salary.MonthlyMin, "salary.monthlyMax": job.Salary.MonthlyMax}}))
+		if len(writes) >= searchBackfillBatch {
+			flush()
+		}
+	}
+	flush()
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return failed
+}
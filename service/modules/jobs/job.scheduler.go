@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"fmt"
+	"hireforwork-server/config"
+	service "hireforwork-server/service/modules"
+	"log"
+	"time"
+)
+
+/*
+1. JobScheduler moves jobs along their lifecycle in the background
+2. Every tick it publishes scheduled jobs, expires overdue ones and sends expiry reminders
+3. Every step claims its jobs atomically, several instances can run it side by side
+*/
+type JobScheduler struct {
+	repo     *JobRepository
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewJobScheduler works on the repository of jobService so the job cache stays in sync
+func NewJobScheduler(jobService *JobService) *JobScheduler {
+	return &JobScheduler{
+		repo:     jobService.repo,
+		interval: config.GetInstance().JobSchedulerTick,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *JobScheduler) Start() {
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.RunOnce()
+		for {
+			select {
+			case <-ticker.C:
+				s.RunOnce()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the running tick to finish
+func (s *JobScheduler) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+func (s *JobScheduler) RunOnce() {
+	now := time.Now()
+
+	if published, err := s.repo.PublishDueJobs(now); err != nil {
+		log.Printf("Error publishing scheduled jobs: %v", err)
+	} else if published > 0 {
+		log.Printf("Published %d scheduled jobs", published)
+	}
+
+	if expired, err := s.repo.ExpireOverdueJobs(now); err != nil {
+		log.Printf("Error expiring jobs: %v", err)
+	} else if expired > 0 {
+		log.Printf("Expired %d jobs", expired)
+	}
+
+	if err := s.sendExpiryReminders(now); err != nil {
+		log.Printf("Error sending job expiry reminders: %v", err)
+	}
+}
+
+// sendExpiryReminders emails each company a one-click link extending its job
+func (s *JobScheduler) sendExpiryReminders(now time.Time) error {
+	expiring, err := s.repo.expiringJobs(now)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range expiring {
+		if job.CompanyEmail == "" {
+			continue
+		}
+		claimed, err := s.repo.claimExpiryReminder(job.Id, now)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		link := fmt.Sprintf("%s/jobs/%s/extend?token=%s", s.repo.baseURL, job.Id.Hex(), s.repo.extendToken(job.Jobs, now))
+		subject := fmt.Sprintf("Bài đăng \"%s\" sắp hết hạn", job.JobTitle)
+		body := fmt.Sprintf(
+			`Xin chào %s,<br>Bài đăng <b>%s</b> sẽ hết hạn vào %s.<br>Bấm vào <a href="%s">liên kết này</a> để gia hạn thêm %d ngày.`,
+			job.CompanyName,
+			job.JobTitle,
+			job.ExpireDate.Time().Format("02/01/2006 15:04"),
+			link,
+			int(s.repo.extendPeriod.Hours()/24),
+		)
+		if err := service.SendEmail(job.CompanyEmail, subject, body); err != nil {
+			log.Printf("Error sending expiry reminder for job %s: %v", job.Id.Hex(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// searchBackfillBatch bounds the writes sent at once while folding older jobs
+const searchBackfillBatch = 500
+
+// textIndex ranks title and tech matches above the company name and the description.
+// Vietnamese has no stemmer, the folded text is indexed word by word.
+var textIndex = mongo.IndexModel{
+	Keys: bson.D{{"search.title", "text"}, {"search.tech", "text"}, {"search.company", "text"}, {"search.body", "text"}},
+	Options: options.Index().
+		SetName("job_text_search").
+		SetDefaultLanguage("none").
+		SetWeights(bson.D{{"search.title", 10}, {"search.tech", 5}, {"search.company", 3}, {"search.body", 1}}),
+}
+
+// jobSearch folds the searchable text of a job for the text index
+func jobSearch(job models.Jobs, companyName string) models.JobSearch {
+	return models.JobSearch{
+		Title:   utils.FoldText(job.JobTitle),
+		Tech:    utils.FoldText(strings.Join(job.JobTech, " ")),
+		Company: utils.FoldText(companyName),
+		Body:    utils.FoldText(job.JobDescription + " " + strings.Join(job.JobRequirement, " ")),
+	}
+}
+
+// textSearchQuery folds user input the way the indexed text was folded and drops the
+// $text operators: quotes would make words mandatory phrases and "-" exclude them
+func textSearchQuery(query string) string {
+	words := strings.FieldsFunc(utils.FoldText(query), func(r rune) bool {
+		return r == ' ' || r == '"' || r == '\\'
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimLeft(word, "-"); word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// backfillSearch folds the text of jobs saved before search fields existed
+func (j *JobRepository) backfillSearch() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"search", bson.D{{"$exists", false}}}}}},
+		{{"$lookup", bson.D{
+			{"from", "Company"},
+			{"localField", "companyID"},
+			{"foreignField", "_id"},
+			{"as", "company"},
+		}}},
+		{{"$set", bson.D{{"companyName", bson.D{{"$first", "$company.companyName"}}}}}},
+		{{"$unset", bson.A{"company"}}},
+	}
+	cursor, err := j.jobCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("reading jobs to backfill search: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	writes := []mongo.WriteModel{}
+	var failed error
+	flush := func() {
+		if len(writes) == 0 {
+			return
+		}
+		if _, err := j.jobCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			failed = fmt.Errorf("backfilling job search: %w", err)
+		}
+		writes = writes[:0]
+	}
+	for cursor.Next(ctx) {
+		var job struct {
+			models.Jobs `bson:",inline"`
+			CompanyName string `bson:"companyName"`
+		}
+		if err := cursor.Decode(&job); err != nil {
+			log.Printf("Error decoding job to backfill search: %v", err)
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": job.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"search": jobSearch(job.Jobs, job.CompanyName)}}))
+		if len(writes) >= searchBackfillBatch {
+			flush()
+		}
+	}
+	flush()
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return failed
+}
@@ -4,6 +4,7 @@ import (
 	"hireforwork-server/db"
 	"hireforwork-server/interfaces"
 	"hireforwork-server/models"
+	"log"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
@@ -24,12 +25,28 @@ func (j *JobService) GetJob(page, pageSize int, filter interfaces.IJobFilter) (b
 	return j.repo.GetJob(page, pageSize, filter)
 }
 
-func (j *JobService) CreateJob(job models.Jobs) (models.Jobs, error) {
-	return j.repo.CreateJob(job)
+func (j *JobService) CreateJob(companyID string, job models.Jobs) (models.Jobs, error) {
+	return j.repo.CreateJob(companyID, job)
 }
 
-func (j *JobService) UpdateJob(job models.Jobs) (models.Jobs, error) {
-	return j.repo.UpdateJob(job)
+func (j *JobService) UpdateJob(companyID string, job models.Jobs) (models.Jobs, error) {
+	return j.repo.UpdateJob(companyID, job)
+}
+
+func (j *JobService) DeleteJob(companyID string, jobID string) error {
+	return j.repo.DeleteJob(companyID, jobID)
+}
+
+func (j *JobService) ForceCloseJob(jobID string, reason string) error {
+	return j.repo.ForceCloseJob(jobID, reason)
+}
+
+func (j *JobService) ChangeStatus(companyID string, jobID string, request interfaces.IJobStatusRequest) (models.Jobs, error) {
+	return j.repo.ChangeStatus(companyID, jobID, request)
+}
+
+func (j *JobService) ExtendJob(token string) (models.Jobs, error) {
+	return j.repo.ExtendJob(token)
 }
 
 func (j *JobService) GetLatestJobs() ([]models.Jobs, error) {
@@ -40,6 +57,23 @@ func (j *JobService) GetJobByID(jobID string, userId string) (bson.M, error) {
 	return j.repo.GetJobByID(jobID, userId)
 }
 
+// RecordView counts a view in the background, the job page does not wait for it
+func (j *JobService) RecordView(jobID string, viewer JobViewer) {
+	go func() {
+		if err := j.repo.RecordView(jobID, viewer); err != nil {
+			log.Printf("Error recording view of job %s: %v", jobID, err)
+		}
+	}()
+}
+
+func (j *JobService) GetViewedJobs(careerID string, page int, pageSize int) (bson.M, error) {
+	return j.repo.GetViewedJobs(careerID, page, pageSize)
+}
+
+func (j *JobService) RecommendJobs(careerID string, page int, pageSize int) (bson.M, error) {
+	return j.repo.RecommendJobs(careerID, page, pageSize)
+}
+
 func (j *JobService) SaveJob(careerID string, jobID string) (bson.M, error) {
 	return j.repo.SaveJob(careerID, jobID)
 }
@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"hireforwork-server/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidJobSort = errors.New("Kiểu sắp xếp không hợp lệ")
+	ErrInvalidCursor  = errors.New("Con trỏ phân trang không hợp lệ")
+)
+
+// Orders of the job listing
+const (
+	JobSortNewest     = "newest"
+	JobSortSalary     = "salary"
+	JobSortRelevance  = "relevance"
+	JobSortExpiring   = "expiring"
+	JobSortPopularity = "popularity"
+	JobSortDistance   = "distance"
+)
+
+const (
+	defaultJobPageSize = 10
+	maxJobPageSize     = 50
+)
+
+// jobSort is the key a listing is ordered by, _id breaks ties so the order is total
+type jobSort struct {
+	key       interface{}
+	direction int
+}
+
+var jobSorts = map[string]jobSort{
+	JobSortNewest:     {"$createAt", -1},
+	JobSortSalary:     {monthlySalary, -1},
+	JobSortRelevance:  {"$score", -1},
+	JobSortExpiring:   {"$expireDate", 1},
+	JobSortPopularity: {bson.D{{"$ifNull", bson.A{"$applicationCount", 0}}}, -1},
+	JobSortDistance:   {"$distanceKm", 1},
+}
+
+// resolveJobSort picks the order of a listing, the closest first when searching near a
+// place, by relevance when searching text and the newest first otherwise. Relevance
+// needs a search query and distance a place.
+func resolveJobSort(sortBy string, searching bool, nearby bool) (string, error) {
+	switch {
+	case sortBy == "" && nearby:
+		return JobSortDistance, nil
+	case sortBy == "" && searching:
+		return JobSortRelevance, nil
+	case sortBy == "" || (sortBy == JobSortRelevance && !searching) || (sortBy == JobSortDistance && !nearby):
+		return JobSortNewest, nil
+	}
+	if _, ok := jobSorts[sortBy]; !ok {
+		return "", ErrInvalidJobSort
+	}
+	return sortBy, nil
+}
+
+// pageBounds defaults a missing page or page size and caps the page size
+func pageBounds(page int, pageSize int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = defaultJobPageSize
+	}
+	if pageSize > maxJobPageSize {
+		pageSize = maxJobPageSize
+	}
+	return page, pageSize
+}
+
+// sortStages orders the jobs by sortBy through a computed sortKey the cursor refers to.
+// Jobs found near center carry their distance from it.
+func sortStages(sortBy string, searching bool, center *models.GeoPoint) []bson.D {
+	stages := []bson.D{}
+	if searching {
+		stages = append(stages, bson.D{{"$addFields", bson.D{{"score", bson.D{{"$meta", "textScore"}}}}}})
+	}
+	if center != nil {
+		stages = append(stages, bson.D{{"$addFields", bson.D{{"distanceKm", distanceKm(*center)}}}})
+	}
+	order := jobSorts[sortBy]
+	return append(stages,
+		bson.D{{"$addFields", bson.D{{"sortKey", order.key}}}},
+		bson.D{{"$sort", bson.D{{"sortKey", order.direction}, {"_id", order.direction}}}},
+	)
+}
+
+// jobCursor is the position after the last job of a page. Paging by position instead of
+// offset keeps infinite scroll stable while new jobs are posted.
+type jobCursor struct {
+	Sort  string             `bson:"s"`
+	Key   interface{}        `bson:"k"`
+	JobID primitive.ObjectID `bson:"id"`
+}
+
+// encodeCursor makes an opaque cursor, keeping the BSON type of the key so it compares
+// like the stored values
+func encodeCursor(sortBy string, key interface{}, jobID primitive.ObjectID) (string, error) {
+	raw, err := bson.Marshal(jobCursor{Sort: sortBy, Key: key, JobID: jobID})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// cursorMatch decodes a cursor of sortBy into the stage matching the jobs after it
+func cursorMatch(cursor string, sortBy string) (bson.D, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var position struct {
+		Sort  string             `bson:"s"`
+		Key   bson.RawValue      `bson:"k"`
+		JobID primitive.ObjectID `bson:"id"`
+	}
+	if err := bson.Unmarshal(raw, &position); err != nil || position.Sort != sortBy || position.JobID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	after := "$lt"
+	if jobSorts[sortBy].direction > 0 {
+		after = "$gt"
+	}
+	return bson.D{{"$match", bson.D{{"$or", bson.A{
+		bson.D{{"sortKey", bson.D{{after, position.Key}}}},
+		bson.D{{"sortKey", position.Key}, {"_id", bson.D{{after, position.JobID}}}},
+	}}}}}, nil
+}
+
+// backfillApplicationCount counts the applications of jobs saved before the counter
+// existed, popularity sorts by it
+func (j *JobRepository) backfillApplicationCount() error {
+	count, err := j.jobCollection.CountDocuments(context.Background(), bson.M{"applicationCount": bson.M{"$exists": false}})
+	if err != nil || count == 0 {
+		return err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{"$group", bson.D{{"_id", "$jobID"}, {"applicationCount", bson.D{{"$sum", 1}}}}}},
+		{{"$merge", bson.D{
+			{"into", j.jobCollection.Name()},
+			{"on", "_id"},
+			{"whenMatched", "merge"},
+			{"whenNotMatched", "discard"},
+		}}},
+	}
+	cursor, err := j.careerApplyCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return err
+	}
+	cursor.Close(context.Background())
+
+	_, err = j.jobCollection.UpdateMany(
+		context.Background(),
+		bson.M{"applicationCount": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"applicationCount": 0}},
+	)
+	return err
+}
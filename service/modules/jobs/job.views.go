@@ -0,0 +1,204 @@
+package jobs
+
+import (
+	"context"
+	"hireforwork-server/models"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxViewedJobs bounds the recently viewed jobs kept per career
+const maxViewedJobs = 50
+
+// JobViewer is who opened a job, a signed in career or an anonymous visitor identified
+// by a hash of its address, so no IP address is stored
+type JobViewer struct {
+	CareerID string
+	Session  string
+}
+
+func (v JobViewer) key() string {
+	if v.CareerID != "" {
+		return "career:" + v.CareerID
+	}
+	return "session:" + v.Session
+}
+
+func (j *JobRepository) ensureViewIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// A viewer counts once per job until its view expires after the dedup window
+	_, err := j.jobViewCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"jobID", 1}, {"viewer", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"viewedAt", 1}}, Options: options.Index().SetExpireAfterSeconds(int32(j.viewWindow.Seconds()))},
+	})
+	if err != nil {
+		log.Printf("Error creating job view indexes: %v", err)
+	}
+	_, err = j.careerViewedCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"careerID", 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Error creating viewed job indexes: %v", err)
+	}
+}
+
+// RecordView counts a view of a live job by viewer, once per dedup window, on the job
+// and its company. Careers also get the job at the top of their recently viewed jobs.
+func (j *JobRepository) RecordView(jobID string, viewer JobViewer) error {
+	_id, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return ErrJobNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	now := time.Now()
+
+	// Nothing is recorded for jobs that are not public
+	var job models.Jobs
+	filter := publicJobFilter()
+	filter["_id"] = _id
+	err = j.jobCollection.FindOne(ctx, filter, options.FindOne().SetProjection(bson.M{"companyID": 1})).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if viewer.CareerID != "" {
+		if err := j.rememberViewedJob(ctx, viewer.CareerID, _id, now); err != nil {
+			log.Printf("Error remembering viewed job %s: %v", jobID, err)
+		}
+	}
+
+	_, err = j.jobViewCollection.InsertOne(ctx, bson.M{
+		"jobID":    _id,
+		"viewer":   viewer.key(),
+		"viewedAt": primitive.NewDateTimeFromTime(now),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.jobCollection.UpdateOne(ctx, bson.M{"_id": _id}, bson.M{"$inc": bson.M{"viewCount": 1}}); err != nil {
+		return err
+	}
+	_, err = j.companyCollection.UpdateOne(ctx, bson.M{"_id": job.CompanyID}, bson.M{"$inc": bson.M{"companyViewed": 1}})
+	return err
+}
+
+// rememberViewedJob moves jobID to the top of the recently viewed jobs of a career and
+// drops the oldest beyond maxViewedJobs
+func (j *JobRepository) rememberViewedJob(ctx context.Context, careerID string, jobID primitive.ObjectID, now time.Time) error {
+	careerObjID, err := primitive.ObjectIDFromHex(careerID)
+	if err != nil {
+		return err
+	}
+	update := mongo.Pipeline{{{"$set", bson.D{{"viewedJob", bson.D{{"$slice", bson.A{
+		bson.D{{"$concatArrays", bson.A{
+			bson.A{bson.D{{"jobID", jobID}, {"viewedAt", primitive.NewDateTimeFromTime(now)}}},
+			bson.D{{"$filter", bson.D{
+				{"input", bson.D{{"$ifNull", bson.A{"$viewedJob", bson.A{}}}}},
+				{"cond", bson.D{{"$ne", bson.A{"$$this.jobID", jobID}}}},
+			}}},
+		}}},
+		maxViewedJobs,
+	}}}}}}}}
+	_, err = j.careerViewedCollection.UpdateOne(ctx, bson.M{"careerID": careerObjID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetViewedJobs lists the jobs a career opened lately, the most recent first. Deleted
+// jobs are left out.
+func (j *JobRepository) GetViewedJobs(careerID string, page int, pageSize int) (bson.M, error) {
+	careerObjID, err := primitive.ObjectIDFromHex(careerID)
+	if err != nil {
+		return nil, err
+	}
+	page, pageSize = pageBounds(page, pageSize)
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"careerID", careerObjID}}}},
+		{{"$unwind", bson.D{{"path", "$viewedJob"}, {"includeArrayIndex", "position"}}}},
+		{{"$lookup", bson.D{
+			{"from", "Job"},
+			{"localField", "viewedJob.jobID"},
+			{"foreignField", "_id"},
+			{"as", "job"},
+		}}},
+		{{"$unwind", "$job"}},
+		{{"$match", bson.D{{"job.isDeleted", false}}}},
+		{{"$lookup", bson.D{
+			{"from", "Company"},
+			{"localField", "job.companyID"},
+			{"foreignField", "_id"},
+			{"as", "company"},
+		}}},
+		{{"$sort", bson.D{{"position", 1}}}},
+		{{"$facet", bson.D{
+			{"totalCount", bson.A{bson.D{{"$count", "count"}}}},
+			{"data", bson.A{
+				bson.D{{"$skip", int64((page - 1) * pageSize)}},
+				bson.D{{"$limit", int64(pageSize)}},
+				bson.D{{"$project", bson.D{
+					{"_id", "$job._id"},
+					{"viewedAt", "$viewedJob.viewedAt"},
+					{"jobTitle", "$job.jobTitle"},
+					{"status", "$job.status"},
+					{"companyID", "$job.companyID"},
+					{"companyName", bson.D{{"$first", "$company.companyName"}}},
+					{"companyImage", bson.D{{"$first", "$company.companyImage"}}},
+					{"jobSalaryMin", publicSalary("$job.jobSalaryMin", "$job.salary.hidden")},
+					{"jobSalaryMax", publicSalary("$job.jobSalaryMax", "$job.salary.hidden")},
+					{"salary", "$job.salary"},
+					{"workingLocation", "$job.workingLocation"},
+					{"workArrangement", "$job.workArrangement"},
+					{"employmentType", "$job.employmentType"},
+					{"expireDate", "$job.expireDate"},
+				}}},
+			}},
+		}}},
+	}
+	cursor, err := j.careerViewedCollection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var result []struct {
+		TotalCount []struct {
+			Count int64 `bson:"count"`
+		} `bson:"totalCount"`
+		Data []bson.M `bson:"data"`
+	}
+	if err := cursor.All(context.Background(), &result); err != nil {
+		return nil, err
+	}
+
+	totalDocs := int64(0)
+	docs := []bson.M{}
+	if len(result) > 0 {
+		if len(result[0].TotalCount) > 0 {
+			totalDocs = result[0].TotalCount[0].Count
+		}
+		docs = append(docs, result[0].Data...)
+	}
+	return bson.M{
+		"docs":        docs,
+		"totalDocs":   totalDocs,
+		"currentPage": page,
+		"totalPage":   int64(math.Ceil(float64(totalDocs) / float64(pageSize))),
+	}, nil
+}
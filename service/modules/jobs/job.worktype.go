@@ -0,0 +1,253 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireforwork-server/constants"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/models"
+	"hireforwork-server/utils"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	// Remote timezones are validated against the IANA database whatever the host has
+	_ "time/tzdata"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidWorkArrangement = errors.New("Hình thức làm việc không hợp lệ")
+	ErrInvalidEmploymentType  = errors.New("Loại hình công việc không hợp lệ")
+)
+
+var workArrangements = map[string]bool{
+	constants.WORK_ONSITE: true,
+	constants.WORK_HYBRID: true,
+	constants.WORK_REMOTE: true,
+}
+
+var employmentTypes = map[string]bool{
+	constants.EMPLOYMENT_FULL_TIME:  true,
+	constants.EMPLOYMENT_PART_TIME:  true,
+	constants.EMPLOYMENT_CONTRACT:   true,
+	constants.EMPLOYMENT_INTERNSHIP: true,
+	constants.EMPLOYMENT_FREELANCE:  true,
+}
+
+var countryCode = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// legacyWorkTypes maps words of the free text workingType values, folded by
+// utils.FoldText, to the arrangement or employment type they mean. Arrangements are
+// listed from the most flexible, which wins when a job mentions several.
+var legacyWorkTypes = []struct {
+	words       []string
+	arrangement string
+	employment  string
+}{
+	{words: []string{"remote", "tu xa", "wfh", "work from home"}, arrangement: constants.WORK_REMOTE},
+	{words: []string{"hybrid", "linh hoat", "ket hop"}, arrangement: constants.WORK_HYBRID},
+	{words: []string{"onsite", "on site", "office", "van phong", "tai cong ty"}, arrangement: constants.WORK_ONSITE},
+	{words: []string{"full time", "fulltime", "toan thoi gian"}, employment: constants.EMPLOYMENT_FULL_TIME},
+	{words: []string{"part time", "parttime", "ban thoi gian"}, employment: constants.EMPLOYMENT_PART_TIME},
+	{words: []string{"contract", "hop dong", "thoi vu"}, employment: constants.EMPLOYMENT_CONTRACT},
+	{words: []string{"intern", "internship", "thuc tap"}, employment: constants.EMPLOYMENT_INTERNSHIP},
+	{words: []string{"freelance", "tu do", "cong tac vien"}, employment: constants.EMPLOYMENT_FREELANCE},
+}
+
+// appendUnique adds value to values unless it is already there
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// normalizeWorkTypes defaults and validates the arrangement and employment types of a
+// job. Jobs are on-site and full time unless they say otherwise.
+func normalizeWorkTypes(job *models.Jobs) error {
+	arrangement := &job.WorkArrangement
+	arrangement.Type = strings.ToUpper(arrangement.Type)
+	if arrangement.Type == "" {
+		arrangement.Type = constants.WORK_ONSITE
+	}
+	if !workArrangements[arrangement.Type] {
+		return ErrInvalidWorkArrangement
+	}
+	// Only remote jobs are limited to countries or timezones
+	if arrangement.Type != constants.WORK_REMOTE && (len(arrangement.Countries) > 0 || len(arrangement.Timezones) > 0) {
+		return ErrInvalidWorkArrangement
+	}
+	countries := []string{}
+	for _, country := range arrangement.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !countryCode.MatchString(country) {
+			return ErrInvalidWorkArrangement
+		}
+		countries = appendUnique(countries, country)
+	}
+	arrangement.Countries = countries
+	timezones := []string{}
+	for _, timezone := range arrangement.Timezones {
+		timezone = strings.TrimSpace(timezone)
+		if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+			return ErrInvalidWorkArrangement
+		}
+		timezones = appendUnique(timezones, timezone)
+	}
+	arrangement.Timezones = timezones
+
+	types := []string{}
+	for _, employment := range job.EmploymentType {
+		employment = strings.ToUpper(employment)
+		if !employmentTypes[employment] {
+			return ErrInvalidEmploymentType
+		}
+		types = appendUnique(types, employment)
+	}
+	if len(types) == 0 {
+		types = append(types, constants.EMPLOYMENT_FULL_TIME)
+	}
+	job.EmploymentType = types
+	return nil
+}
+
+// normalizeWorkTypeFilter validates the arrangement, employment type and country a
+// search filters by
+func normalizeWorkTypeFilter(filter interfaces.IJobFilter) (interfaces.IJobFilter, error) {
+	arrangements := []string{}
+	for _, arrangement := range filter.WorkArrangement {
+		arrangement = strings.ToUpper(arrangement)
+		if !workArrangements[arrangement] {
+			return filter, ErrInvalidWorkArrangement
+		}
+		arrangements = append(arrangements, arrangement)
+	}
+	filter.WorkArrangement = arrangements
+	types := []string{}
+	for _, employment := range filter.EmploymentType {
+		employment = strings.ToUpper(employment)
+		if !employmentTypes[employment] {
+			return filter, ErrInvalidEmploymentType
+		}
+		types = append(types, employment)
+	}
+	filter.EmploymentType = types
+	filter.RemoteCountry = strings.ToUpper(filter.RemoteCountry)
+	if filter.RemoteCountry != "" && !countryCode.MatchString(filter.RemoteCountry) {
+		return filter, ErrInvalidWorkArrangement
+	}
+	return filter, nil
+}
+
+// remoteFrom matches the remote jobs open to people living in country
+func remoteFrom(country string) bson.M {
+	return bson.M{
+		"workArrangement.type": constants.WORK_REMOTE,
+		"$or": bson.A{
+			bson.M{"workArrangement.countries": bson.M{"$in": bson.A{nil, bson.A{}}}},
+			bson.M{"workArrangement.countries": country},
+		},
+	}
+}
+
+// parseLegacyWorkTypes reads the arrangement and employment types out of free text
+// workingType values, what they do not mention gets the defaults of normalizeWorkTypes
+func parseLegacyWorkTypes(values []string) (models.WorkArrangement, []string) {
+	text := " "
+	for _, value := range values {
+		text += utils.FoldText(strings.NewReplacer("-", " ", "_", " ", "/", " ", ",", " ").Replace(value)) + " "
+	}
+
+	arrangement := models.WorkArrangement{Type: constants.WORK_ONSITE}
+	arrangementFound := false
+	types := []string{}
+	for _, legacy := range legacyWorkTypes {
+		for _, word := range legacy.words {
+			if !strings.Contains(text, " "+word+" ") {
+				continue
+			}
+			if legacy.arrangement != "" && !arrangementFound {
+				arrangement.Type = legacy.arrangement
+				arrangementFound = true
+			}
+			if legacy.employment != "" {
+				types = appendUnique(types, legacy.employment)
+			}
+			break
+		}
+	}
+	if len(types) == 0 {
+		types = append(types, constants.EMPLOYMENT_FULL_TIME)
+	}
+	return arrangement, types
+}
+
+// migrateWorkTypes gives older jobs a validated arrangement and employment types read
+// from their free text workingType. The legacy field is left in place for instances
+// still running the previous release.
+func (j *JobRepository) migrateWorkTypes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	filter := bson.M{"workArrangement": bson.M{"$exists": false}}
+	opts := options.Find().SetProjection(bson.M{"workingType": 1})
+	cursor, err := j.jobCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return fmt.Errorf("reading job work types: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	writes := []mongo.WriteModel{}
+	var failed error
+	flush := func() {
+		if len(writes) == 0 {
+			return
+		}
+		if _, err := j.jobCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			failed = fmt.Errorf("migrating job work types: %w", err)
+		}
+		writes = writes[:0]
+	}
+	for cursor.Next(ctx) {
+		var job struct {
+			Id          primitive.ObjectID `bson:"_id"`
+			WorkingType interface{}        `bson:"workingType"`
+		}
+		if err := cursor.Decode(&job); err != nil {
+			log.Printf("Error decoding job work types: %v", err)
+			continue
+		}
+		// Some clients saved a single string instead of a list
+		values := []string{}
+		switch workingType := job.WorkingType.(type) {
+		case string:
+			values = append(values, workingType)
+		case bson.A:
+			for _, value := range workingType {
+				if text, ok := value.(string); ok {
+					values = append(values, text)
+				}
+			}
+		}
+		arrangement, types := parseLegacyWorkTypes(values)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": job.Id}).
+			SetUpdate(bson.M{"$set": bson.M{"workArrangement": arrangement, "employmentType": types}}))
+		if len(writes) >= searchBackfillBatch {
+			flush()
+		}
+	}
+	flush()
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return failed
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/constants"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrAccountNotFound     = errors.New("Không tìm thấy tài khoản")
+	ErrInvalidAccountState = errors.New("Trạng thái tài khoản không hợp lệ")
+)
+
+// accountStatusFilter selects careers or companies by moderation status, public
+// listings only ever use the default (active accounts)
+func accountStatusFilter(status string) (bson.D, error) {
+	switch status {
+	case "", constants.ACCOUNT_ACTIVE:
+		return bson.D{{"isDeleted", false}, {"isSuspended", bson.M{"$ne": true}}}, nil
+	case constants.ACCOUNT_SUSPENDED:
+		return bson.D{{"isDeleted", false}, {"isSuspended", true}}, nil
+	case constants.ACCOUNT_DELETED:
+		return bson.D{{"isDeleted", true}}, nil
+	case constants.ACCOUNT_ANY:
+		return bson.D{}, nil
+	default:
+		return nil, ErrInvalidAccountState
+	}
+}
+
+// suspendAccount blocks a live account, its sessions are revoked by the caller
+func suspendAccount(collection *mongo.Collection, accountID string, reason string) error {
+	_id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return ErrAccountNotFound
+	}
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": _id, "isDeleted": false},
+		bson.M{"$set": bson.M{
+			"isSuspended":   true,
+			"suspendedAt":   primitive.NewDateTimeFromTime(time.Now()),
+			"suspendReason": reason,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// restoreAccount lifts a suspension and brings back a soft deleted account
+func restoreAccount(collection *mongo.Collection, accountID string) error {
+	_id, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return ErrAccountNotFound
+	}
+
+	result, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": _id},
+		bson.M{
+			"$set":   bson.M{"isDeleted": false},
+			"$unset": bson.M{"isSuspended": "", "suspendedAt": "", "suspendReason": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"hireforwork-server/config"
+	"hireforwork-server/constants"
+	"hireforwork-server/db"
+	"hireforwork-server/models"
+	"hireforwork-server/service/modules/auth"
+	"hireforwork-server/service/modules/ratelimit"
+	"hireforwork-server/utils"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxResetAttempts wrong codes lock the reset, a new one has to be requested
+const maxResetAttempts = 5
+
+var (
+	ErrInvalidResetCode       = errors.New("Mã xác nhận không hợp lệ hoặc đã hết hạn")
+	ErrPasswordResetThrottled = errors.New("Bạn đã yêu cầu quá nhiều lần, vui lòng thử lại sau")
+	ErrEmptyPassword          = errors.New("Mật khẩu mới không được để trống")
+)
+
+var resetIndexOnce sync.Once
+
+type PasswordResetService struct {
+	careerCollection  *mongo.Collection
+	companyCollection *mongo.Collection
+	resetCollection   *mongo.Collection
+	authService       *auth.AuthService
+	limiter           *ratelimit.Limiter
+	ttl               time.Duration
+}
+
+func NewPasswordResetService(dbInstance *db.DB) *PasswordResetService {
+	collections := dbInstance.GetCollections([]string{"Career", "Company", "PasswordReset"})
+
+	passwordResetService := &PasswordResetService{
+		careerCollection:  collections[0],
+		companyCollection: collections[1],
+		resetCollection:   collections[2],
+		authService:       auth.NewAuthService(dbInstance),
+		limiter:           ratelimit.NewLimiter(dbInstance),
+		ttl:               config.GetInstance().PasswordResetTTL,
+	}
+	resetIndexOnce.Do(passwordResetService.ensureIndexes)
+
+	return passwordResetService
+}
+
+func (p *PasswordResetService) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := p.resetCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"accountType", 1}, {"email", 1}}},
+		{Keys: bson.D{{"expireAt", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("Error creating password reset indexes: %v", err)
+	}
+}
+
+func (p *PasswordResetService) collection(accountType string) *mongo.Collection {
+	if accountType == constants.COMPANY {
+		return p.companyCollection
+	}
+	return p.careerCollection
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// RequestReset emails a new reset code and invalidates the previous ones. The code is
+// issued in the background and nil returned whether the account exists or not, so
+// neither the answer nor its timing tells callers which emails are registered.
+func (p *PasswordResetService) RequestReset(accountType string, email string, ip string) error {
+	email = strings.TrimSpace(email)
+	if allowed, _ := p.limiter.Allow("reset:email:"+accountType+":"+normalizeEmail(email), 3, time.Hour); !allowed {
+		return ErrPasswordResetThrottled
+	}
+	if allowed, _ := p.limiter.Allow("reset:ip:"+ip, 10, time.Hour); !allowed {
+		return ErrPasswordResetThrottled
+	}
+
+	go func() {
+		if err := p.issueReset(accountType, email); err != nil {
+			log.Printf("Error issuing password reset: %v", err)
+		}
+	}()
+	return nil
+}
+
+// issueReset stores and emails a reset code when email belongs to an account
+func (p *PasswordResetService) issueReset(accountType string, email string) error {
+	var account struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	err := p.collection(accountType).FindOne(context.Background(), bson.M{
+		emailField(accountType): email,
+		"isDeleted":             false,
+	}).Decode(&account)
+	if err != nil {
+		return nil
+	}
+
+	code, err := utils.RandomToken(24)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.resetCollection.UpdateMany(
+		context.Background(),
+		bson.M{"accountID": account.Id, "isUsed": false},
+		bson.M{"$set": bson.M{"isUsed": true}},
+	)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = p.resetCollection.InsertOne(context.Background(), models.PasswordReset{
+		Id:          primitive.NewObjectID(),
+		AccountType: accountType,
+		AccountID:   account.Id,
+		Email:       normalizeEmail(email),
+		CodeHash:    utils.HashToken(code),
+		CreateAt:    primitive.NewDateTimeFromTime(now),
+		ExpireAt:    primitive.NewDateTimeFromTime(now.Add(p.ttl)),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject := "Mã xác nhận khôi phục mật khẩu"
+	body := fmt.Sprintf("Mã xác nhận của bạn là: %s<br>Mã có hiệu lực trong %s và chỉ dùng được một lần.", code, p.ttl)
+	if err := SendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("Lỗi khi gửi email: %v", err)
+	}
+	return nil
+}
+
+// ResetPassword consumes the active code of the email. Every wrong code counts as an
+// attempt and the code is locked after maxResetAttempts.
+func (p *PasswordResetService) ResetPassword(accountType string, email string, code string, newPassword string) error {
+	if newPassword == "" {
+		return ErrEmptyPassword
+	}
+
+	var reset models.PasswordReset
+	err := p.resetCollection.FindOne(context.Background(), bson.M{
+		"accountType": accountType,
+		"email":       normalizeEmail(email),
+		"isUsed":      false,
+		"expireAt":    bson.M{"$gt": time.Now()},
+	}, options.FindOne().SetSort(bson.D{{"createAt", -1}})).Decode(&reset)
+	if err != nil {
+		return ErrInvalidResetCode
+	}
+	if reset.Attempts >= maxResetAttempts {
+		return ErrInvalidResetCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(reset.CodeHash), []byte(utils.HashToken(code))) != 1 {
+		_, err := p.resetCollection.UpdateOne(context.Background(), bson.M{"_id": reset.Id}, bson.M{"$inc": bson.M{"attempts": 1}})
+		if err != nil {
+			log.Printf("Error counting reset attempt for %s: %v", reset.Id.Hex(), err)
+		}
+		return ErrInvalidResetCode
+	}
+
+	// Consume atomically so the same code cannot be redeemed twice
+	result, err := p.resetCollection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": reset.Id, "isUsed": false, "attempts": bson.M{"$lt": maxResetAttempts}},
+		bson.M{"$set": bson.M{"isUsed": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return ErrInvalidResetCode
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("Lỗi khi cập nhật mật khẩu mới: %v", err)
+	}
+	_, err = p.collection(accountType).UpdateOne(
+		context.Background(),
+		bson.M{"_id": reset.AccountID},
+		bson.M{
+			"$set":   bson.M{"password": hashedPassword},
+			"$unset": bson.M{"verificationCode": ""},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("Lỗi khi cập nhật mật khẩu mới: %v", err)
+	}
+
+	p.authService.RevokeAllSessions(reset.AccountID)
+	return nil
+}
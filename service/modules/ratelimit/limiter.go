@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"hireforwork-server/db"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+/*
+1. Fixed window counters stored in Mongo so every serverless instance shares them
+2. Documents expire through a TTL index once their window is over
+*/
+type Limiter struct {
+	collection *mongo.Collection
+}
+
+type counter struct {
+	Key      string    `bson:"_id"`
+	Count    int       `bson:"count"`
+	ExpireAt time.Time `bson:"expireAt"`
+}
+
+var indexOnce sync.Once
+
+func NewLimiter(dbInstance *db.DB) *Limiter {
+	limiter := &Limiter{collection: dbInstance.GetCollection("RateLimit")}
+	indexOnce.Do(limiter.ensureIndexes)
+	return limiter
+}
+
+func (l *Limiter) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := l.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"expireAt", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("Error creating rate limit indexes: %v", err)
+	}
+}
+
+// Allow records one hit for key and reports whether it is still within limit for the
+// current window, along with the time left until the window resets.
+// Storage errors fail open so an outage does not lock everyone out.
+func (l *Limiter) Allow(key string, limit int, window time.Duration) (bool, time.Duration) {
+	now := time.Now()
+	windowStart := now.Truncate(window)
+	windowEnd := windowStart.Add(window)
+
+	var result counter
+	err := l.collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": fmt.Sprintf("%s:%d", key, windowStart.Unix())},
+		bson.M{
+			"$inc":         bson.M{"count": 1},
+			"$setOnInsert": bson.M{"expireAt": windowEnd},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&result)
+	if err != nil {
+		log.Printf("Error counting rate limit for %s: %v", key, err)
+		return true, 0
+	}
+
+	return result.Count <= limit, windowEnd.Sub(now)
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"hireforwork-server/constants"
+	"hireforwork-server/db"
+	"hireforwork-server/interfaces"
+	"hireforwork-server/models"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrReportTargetNotFound = errors.New("Không tìm thấy nội dung cần báo cáo")
+	ErrReportExists         = errors.New("Bạn đã báo cáo nội dung này")
+	ErrReportNotFound       = errors.New("Không tìm thấy báo cáo")
+	ErrInvalidReport        = errors.New("Vui lòng cho biết lý do báo cáo")
+	ErrInvalidReportStatus  = errors.New("Trạng thái báo cáo không hợp lệ")
+)
+
+// maxReportLength keeps free-form report text to a reasonable size
+const maxReportLength = 1000
+
+var reportIndexOnce sync.Once
+
+type ReportService struct {
+	reportCollection  *mongo.Collection
+	jobCollection     *mongo.Collection
+	companyCollection *mongo.Collection
+}
+
+func NewReportService(dbInstance *db.DB) *ReportService {
+	c := dbInstance.GetCollections([]string{"Report", "Job", "Company"})
+	reportService := &ReportService{
+		reportCollection:  c[0],
+		jobCollection:     c[1],
+		companyCollection: c[2],
+	}
+	reportIndexOnce.Do(reportService.ensureIndexes)
+	return reportService
+}
+
+func (r *ReportService) ensureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.reportCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"status", 1}, {"createAt", -1}}},
+		{Keys: bson.D{{"targetType", 1}, {"targetID", 1}, {"reporterID", 1}}},
+	})
+	if err != nil {
+		log.Printf("Error creating report indexes: %v", err)
+	}
+}
+
+func (r *ReportService) targetCollection(targetType string) *mongo.Collection {
+	switch targetType {
+	case constants.REPORT_TARGET_JOB:
+		return r.jobCollection
+	case constants.REPORT_TARGET_COMPANY:
+		return r.companyCollection
+	default:
+		return nil
+	}
+}
+
+// CreateReport flags a live job or company, a reporter has one open report per target
+func (r *ReportService) CreateReport(reporterID string, targetType string, targetID string, request interfaces.IReportRequest) (models.Report, error) {
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" || len(reason) > maxReportLength || len(request.Detail) > maxReportLength {
+		return models.Report{}, ErrInvalidReport
+	}
+
+	reporterObjectID, err := primitive.ObjectIDFromHex(reporterID)
+	if err != nil {
+		return models.Report{}, ErrInvalidReport
+	}
+	targetObjectID, err := primitive.ObjectIDFromHex(targetID)
+	collection := r.targetCollection(targetType)
+	if err != nil || collection == nil {
+		return models.Report{}, ErrReportTargetNotFound
+	}
+
+	count, err := collection.CountDocuments(context.Background(), bson.M{"_id": targetObjectID, "isDeleted": false})
+	if err != nil {
+		return models.Report{}, err
+	}
+	if count == 0 {
+		return models.Report{}, ErrReportTargetNotFound
+	}
+
+	count, err = r.reportCollection.CountDocuments(context.Background(), bson.M{
+		"targetType": targetType,
+		"targetID":   targetObjectID,
+		"reporterID": reporterObjectID,
+		"status":     constants.REPORT_OPEN,
+	})
+	if err != nil {
+		return models.Report{}, err
+	}
+	if count > 0 {
+		return models.Report{}, ErrReportExists
+	}
+
+	report := models.Report{
+		Id:         primitive.NewObjectID(),
+		TargetType: targetType,
+		TargetID:   targetObjectID,
+		ReporterID: reporterObjectID,
+		Reason:     reason,
+		Detail:     strings.TrimSpace(request.Detail),
+		Status:     constants.REPORT_OPEN,
+		CreateAt:   primitive.NewDateTimeFromTime(time.Now()),
+	}
+	if _, err := r.reportCollection.InsertOne(context.Background(), report); err != nil {
+		return models.Report{}, err
+	}
+	return report, nil
+}
+
+// ListReports returns reports for the moderators, oldest open reports first
+func (r *ReportService) ListReports(filter interfaces.IReportFilter) (models.PaginateDocs[models.Report], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = 20
+	}
+
+	bsonFilter := bson.D{}
+	if filter.Status != "" {
+		bsonFilter = append(bsonFilter, bson.E{"status", filter.Status})
+	}
+	if filter.TargetType != "" {
+		bsonFilter = append(bsonFilter, bson.E{"targetType", filter.TargetType})
+	}
+	if filter.TargetID != "" {
+		targetObjectID, err := primitive.ObjectIDFromHex(filter.TargetID)
+		if err != nil {
+			return models.PaginateDocs[models.Report]{}, ErrReportTargetNotFound
+		}
+		bsonFilter = append(bsonFilter, bson.E{"targetID", targetObjectID})
+	}
+
+	totalDocs, err := r.reportCollection.CountDocuments(context.Background(), bsonFilter)
+	if err != nil {
+		return models.PaginateDocs[models.Report]{}, err
+	}
+
+	findOption := options.Find().
+		SetSort(bson.D{{"createAt", 1}}).
+		SetSkip(int64((filter.Page - 1) * filter.PageSize)).
+		SetLimit(int64(filter.PageSize))
+	cursor, err := r.reportCollection.Find(context.Background(), bsonFilter, findOption)
+	if err != nil {
+		return models.PaginateDocs[models.Report]{}, err
+	}
+	defer cursor.Close(context.Background())
+
+	reports := []models.Report{}
+	if err := cursor.All(context.Background(), &reports); err != nil {
+		return models.PaginateDocs[models.Report]{}, err
+	}
+
+	return models.PaginateDocs[models.Report]{
+		Docs:        reports,
+		TotalDocs:   totalDocs,
+		CurrentPage: int64(filter.Page),
+		TotalPage:   int64(math.Ceil(float64(totalDocs) / float64(filter.PageSize))),
+	}, nil
+}
+
+// ResolveReport closes an open report as resolved (action taken) or dismissed
+func (r *ReportService) ResolveReport(reportID string, resolvedBy string, resolve interfaces.IReportResolve) (models.Report, error) {
+	if resolve.Status != constants.REPORT_RESOLVED && resolve.Status != constants.REPORT_DISMISSED {
+		return models.Report{}, ErrInvalidReportStatus
+	}
+	_id, err := primitive.ObjectIDFromHex(reportID)
+	if err != nil {
+		return models.Report{}, ErrReportNotFound
+	}
+
+	var report models.Report
+	err = r.reportCollection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": _id, "status": constants.REPORT_OPEN},
+		bson.M{"$set": bson.M{
+			"status":         resolve.Status,
+			"resolvedBy":     resolvedBy,
+			"resolutionNote": strings.TrimSpace(resolve.Note),
+			"resolvedAt":     primitive.NewDateTimeFromTime(time.Now()),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&report)
+	if err == mongo.ErrNoDocuments {
+		return models.Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+	return report, nil
+}
@@ -2,14 +2,34 @@ package service
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"hireforwork-server/db"
+	"time"
 
+	"github.com/patrickmn/go-cache"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrInvalidStaticRange = errors.New("Khoảng thời gian thống kê không hợp lệ")
+
+// Buckets of the statistics time series
+const (
+	StaticIntervalDay  = "day"
+	StaticIntervalWeek = "week"
+)
+
+const (
+	// defaultStaticRange is used when the dashboard does not pick a range
+	defaultStaticRange = 30 * 24 * time.Hour
+	// maxStaticBuckets keeps a series small enough to compute and chart
+	maxStaticBuckets = 366
+	staticCacheTTL   = 5 * time.Minute
+)
+
 // NewUser struct using primitive.ObjectID
 type NewUser struct {
 	Id            primitive.ObjectID `json:"_id"` // Use ObjectID directly
@@ -26,34 +46,57 @@ type NewPost struct {
 
 // Static struct containing the counts and new users and posts
 type Static struct {
-	TotalUser    int64     `json:"totalUser"`
-	TotalCompany int64     `json:"totalCompany"`
-	TotalPost    int64     `json:"totalPost"`
-	TotalResume  int64     `json:"totalResume"`
-	NewUser      []NewUser `json:"newUser"`
-	NewPost      []NewPost `json:"newPost"`
+	TotalUser        int64     `json:"totalUser"`
+	TotalCompany     int64     `json:"totalCompany"`
+	TotalPost        int64     `json:"totalPost"`
+	TotalResume      int64     `json:"totalResume"`
+	TotalApplication int64     `json:"totalApplication"`
+	NewUser          []NewUser `json:"newUser"`
+	NewPost          []NewPost `json:"newPost"`
+}
+
+// StaticPoint counts what was created during one bucket of the series
+type StaticPoint struct {
+	Period               string    `json:"period"`
+	Start                time.Time `json:"start"`
+	CareerRegistrations  int64     `json:"careerRegistrations"`
+	CompanyRegistrations int64     `json:"companyRegistrations"`
+	JobPosts             int64     `json:"jobPosts"`
+	Applications         int64     `json:"applications"`
+}
+
+// StaticDashboard is the admin dashboard: current totals plus activity over a date range
+type StaticDashboard struct {
+	Static
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Interval string        `json:"interval"`
+	Series   []StaticPoint `json:"series"`
 }
 
 type StaticService struct {
-	userCollection    *mongo.Collection
-	companyCollection *mongo.Collection
-	jobCollection     *mongo.Collection
+	userCollection        *mongo.Collection
+	companyCollection     *mongo.Collection
+	jobCollection         *mongo.Collection
+	applicationCollection *mongo.Collection
+	cache                 *cache.Cache
 }
 
-func NewStaticService(userCollection, companyCollection, jobCollection *mongo.Collection) *StaticService {
-	if userCollection == nil || companyCollection == nil || jobCollection == nil {
-		log.Fatalf("Failed in StaticService")
-	}
+func NewStaticService(dbInstance *db.DB) *StaticService {
+	c := dbInstance.GetCollections([]string{"Career", "Company", "Job", "CareerApplyJob"})
 	return &StaticService{
-		userCollection:    userCollection,
-		companyCollection: companyCollection,
-		jobCollection:     jobCollection,
+		userCollection:        c[0],
+		companyCollection:     c[1],
+		jobCollection:         c[2],
+		applicationCollection: c[3],
+		cache:                 cache.New(staticCacheTTL, 2*staticCacheTTL),
 	}
 }
 
 // GetStatic function to gather statistics
-func (s *StaticService) GetStatic() Static {
+func (s *StaticService) GetStatic() (Static, error) {
 	var static Static
+	var err error
 
 	// Filter to exclude deleted entries
 	filter := bson.M{
@@ -61,16 +104,27 @@ func (s *StaticService) GetStatic() Static {
 	}
 
 	// Count documents for users, companies, and job posts
-	static.TotalUser, _ = s.userCollection.CountDocuments(context.Background(), filter)
-	static.TotalCompany, _ = s.companyCollection.CountDocuments(context.Background(), filter)
-	static.TotalPost, _ = s.jobCollection.CountDocuments(context.Background(), filter)
+	if static.TotalUser, err = s.userCollection.CountDocuments(context.Background(), filter); err != nil {
+		return Static{}, err
+	}
+	if static.TotalCompany, err = s.companyCollection.CountDocuments(context.Background(), filter); err != nil {
+		return Static{}, err
+	}
+	if static.TotalPost, err = s.jobCollection.CountDocuments(context.Background(), filter); err != nil {
+		return Static{}, err
+	}
+	if static.TotalApplication, err = s.applicationCollection.CountDocuments(context.Background(), filter); err != nil {
+		return Static{}, err
+	}
 
-	// Filter for resumes
+	// Careers with at least one uploaded resume
 	resumeFilter := bson.M{
-		"isDeleted": false,
-		"careerCV":  bson.M{"$ne": nil},
+		"isDeleted":        false,
+		"profile.userCV.0": bson.M{"$exists": true},
+	}
+	if static.TotalResume, err = s.userCollection.CountDocuments(context.Background(), resumeFilter); err != nil {
+		return Static{}, err
 	}
-	static.TotalResume, _ = s.userCollection.CountDocuments(context.Background(), resumeFilter)
 
 	// Options for limiting to 5 documents and sorting by createdAt in descending order
 	opt := options.Find().SetLimit(5).SetSort(bson.M{"createAt": -1})
@@ -78,11 +132,11 @@ func (s *StaticService) GetStatic() Static {
 	// Fetch the newest users
 	cur1, err := s.userCollection.Find(context.Background(), filter, opt)
 	if err != nil {
-		log.Fatal(err)
+		return Static{}, err
 	}
 	defer cur1.Close(context.Background())
 
-	var newUsers []NewUser
+	newUsers := []NewUser{}
 	for cur1.Next(context.Background()) {
 		var user struct {
 			ID            primitive.ObjectID `bson:"_id"`
@@ -90,7 +144,7 @@ func (s *StaticService) GetStatic() Static {
 			CareerEmail   string             `bson:"careerEmail"`
 		}
 		if err := cur1.Decode(&user); err != nil {
-			log.Fatal(err)
+			return Static{}, err
 		}
 
 		// Add the decoded user to the newUsers slice
@@ -106,11 +160,11 @@ func (s *StaticService) GetStatic() Static {
 	// Fetch the newest job posts
 	cur2, err := s.jobCollection.Find(context.Background(), filter, opt)
 	if err != nil {
-		log.Fatal(err)
+		return Static{}, err
 	}
 	defer cur2.Close(context.Background())
 
-	var newPosts []NewPost
+	newPosts := []NewPost{}
 	for cur2.Next(context.Background()) {
 		var post struct {
 			ID         primitive.ObjectID `bson:"_id"`
@@ -118,7 +172,7 @@ func (s *StaticService) GetStatic() Static {
 			ExpireDate primitive.DateTime `bson:"expireDate"`
 		}
 		if err := cur2.Decode(&post); err != nil {
-			log.Fatal(err)
+			return Static{}, err
 		}
 
 		// Add the decoded job post to the newPosts slice
@@ -131,5 +185,159 @@ func (s *StaticService) GetStatic() Static {
 	}
 	static.NewPost = newPosts
 
-	return static
+	return static, nil
+}
+
+// GetDashboard returns the totals plus a daily or weekly series over [from, to] (UTC
+// dates, "2006-01-02"). The series counts everything created in the range, deleted
+// or not, so past activity does not change afterwards. Results are cached briefly.
+func (s *StaticService) GetDashboard(from string, to string, interval string) (StaticDashboard, error) {
+	start, end, err := staticRange(from, to, interval)
+	if err != nil {
+		return StaticDashboard{}, err
+	}
+	if interval == "" {
+		interval = StaticIntervalDay
+	}
+
+	cacheKey := fmt.Sprintf("%s|%s|%s", start.Format("2006-01-02"), end.Format("2006-01-02"), interval)
+	if cached, found := s.cache.Get(cacheKey); found {
+		return cached.(StaticDashboard), nil
+	}
+
+	static, err := s.GetStatic()
+	if err != nil {
+		return StaticDashboard{}, err
+	}
+
+	series, buckets := emptySeries(start, end, interval)
+	for _, source := range []struct {
+		collection *mongo.Collection
+		add        func(point *StaticPoint, count int64)
+	}{
+		{s.userCollection, func(point *StaticPoint, count int64) { point.CareerRegistrations = count }},
+		{s.companyCollection, func(point *StaticPoint, count int64) { point.CompanyRegistrations = count }},
+		{s.jobCollection, func(point *StaticPoint, count int64) { point.JobPosts = count }},
+		{s.applicationCollection, func(point *StaticPoint, count int64) { point.Applications = count }},
+	} {
+		counts, err := countByPeriod(source.collection, start, end, interval)
+		if err != nil {
+			return StaticDashboard{}, err
+		}
+		for period, count := range counts {
+			if index, ok := buckets[period]; ok {
+				source.add(&series[index], count)
+			}
+		}
+	}
+
+	dashboard := StaticDashboard{
+		Static:   static,
+		From:     start.Format("2006-01-02"),
+		To:       end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Interval: interval,
+		Series:   series,
+	}
+	s.cache.Set(cacheKey, dashboard, cache.DefaultExpiration)
+	return dashboard, nil
+}
+
+// staticRange parses the requested dates into [start, end) at UTC midnight,
+// defaulting to the last 30 days
+func staticRange(from string, to string, interval string) (time.Time, time.Time, error) {
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidStaticRange
+		}
+		end = parsed.AddDate(0, 0, 1)
+	}
+
+	start := end.Add(-defaultStaticRange)
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidStaticRange
+		}
+		start = parsed
+	}
+
+	days := int(end.Sub(start).Hours() / 24)
+	switch interval {
+	case "", StaticIntervalDay:
+	case StaticIntervalWeek:
+		days = days / 7
+	default:
+		return time.Time{}, time.Time{}, ErrInvalidStaticRange
+	}
+	if !start.Before(end) || days > maxStaticBuckets {
+		return time.Time{}, time.Time{}, ErrInvalidStaticRange
+	}
+	return start, end, nil
+}
+
+// periodKey names the bucket of t the same way the aggregation does
+func periodKey(t time.Time, interval string) string {
+	if interval == StaticIntervalWeek {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	}
+	return t.Format("2006-01-02")
+}
+
+// emptySeries lays out every bucket of the range so periods without activity show as zero
+func emptySeries(start time.Time, end time.Time, interval string) ([]StaticPoint, map[string]int) {
+	series := []StaticPoint{}
+	buckets := map[string]int{}
+
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := periodKey(day, interval)
+		if _, ok := buckets[key]; ok {
+			continue
+		}
+		buckets[key] = len(series)
+		series = append(series, StaticPoint{Period: key, Start: day})
+	}
+	return series, buckets
+}
+
+// countByPeriod groups the documents created in [start, end) by day or ISO week
+func countByPeriod(collection *mongo.Collection, start time.Time, end time.Time, interval string) (map[string]int64, error) {
+	format := "%Y-%m-%d"
+	if interval == StaticIntervalWeek {
+		format = "%G-W%V"
+	}
+
+	pipeline := mongo.Pipeline{
+		{{"$match", bson.D{{"createAt", bson.D{
+			{"$gte", primitive.NewDateTimeFromTime(start)},
+			{"$lt", primitive.NewDateTimeFromTime(end)},
+		}}}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"$dateToString", bson.D{{"format", format}, {"date", "$createAt"}, {"timezone", "UTC"}}}}},
+			{"count", bson.D{{"$sum", 1}}},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var rows []struct {
+		Period string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(context.Background(), &rows); err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.Period] = row.Count
+	}
+	return counts, nil
 }
@@ -3,15 +3,16 @@ package service
 import (
 	"context"
 	"fmt"
+	"hireforwork-server/constants"
 	"hireforwork-server/db"
 	"hireforwork-server/models"
 	"hireforwork-server/service/modules/unit_of_work"
 	"hireforwork-server/utils"
 	"log"
 	"math"
-	"math/rand"
 	"net/http"
-	"time"
+	"regexp"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -25,15 +26,18 @@ type UserService struct {
 	jobCollection         *mongo.Collection
 	userApplyCollection   *mongo.Collection
 	uow                   *unit_of_work.UnitOfWork
+	verification          *VerificationService
+	passwordReset         *PasswordResetService
 }
 
 // Dependency Injection (DI)
 func NewUserService(dbInstance *db.DB) *UserService {
 	collections := dbInstance.GetCollections([]string{"Career", "Job", "CareerSaveJob", "CareerApplyJob"})
-	return &UserService{userCollection: collections[0], userSaveJobCollection: collections[1], jobCollection: collections[2], userApplyCollection: collections[3], uow: unit_of_work.NewUnitOfWork(dbInstance)}
+	return &UserService{userCollection: collections[0], userSaveJobCollection: collections[1], jobCollection: collections[2], userApplyCollection: collections[3], uow: unit_of_work.NewUnitOfWork(dbInstance), verification: NewVerificationService(dbInstance), passwordReset: NewPasswordResetService(dbInstance)}
 }
 
-func (u *UserService) GetUser(page, pageSize int, careerFirstName, lastName, careerEmail, careerPhone string) (models.PaginateDocs[models.User], error) {
+// GetUser lists careers, status selects active (default), suspended, deleted or any accounts
+func (u *UserService) GetUser(page, pageSize int, careerFirstName, lastName, careerEmail, careerPhone string, isVerified *bool, status string) (models.PaginateDocs[models.User], error) {
 	var users []models.User
 	if page < 1 {
 		page = 1
@@ -42,7 +46,10 @@ func (u *UserService) GetUser(page, pageSize int, careerFirstName, lastName, car
 		pageSize = 10
 	}
 
-	bsonFilter := bson.D{{"isDeleted", false}}
+	bsonFilter, err := accountStatusFilter(status)
+	if err != nil {
+		return models.PaginateDocs[models.User]{}, err
+	}
 
 	skip := (page - 1) * pageSize
 
@@ -66,6 +73,10 @@ func (u *UserService) GetUser(page, pageSize int, careerFirstName, lastName, car
 		bsonFilter = append(bsonFilter, bson.E{"careerPhone", bson.D{{"$regex", careerPhone}, {"$options", "i"}}})
 	}
 
+	if isVerified != nil {
+		bsonFilter = append(bsonFilter, bson.E{"isVerified", *isVerified})
+	}
+
 	totalDocs, _ := u.userCollection.CountDocuments(context.Background(), bsonFilter)
 	totalPage := int64(math.Ceil(float64(totalDocs) / float64(pageSize)))
 	cursor, err := u.userCollection.Find(context.Background(), bsonFilter, findOption)
@@ -94,7 +105,9 @@ func (u *UserService) GetUserByID(careerID string) (models.User, error) {
 	_id, _ := primitive.ObjectIDFromHex(careerID)
 	var user models.User
 
-	err := u.userCollection.FindOne(context.Background(), bson.D{{"_id", _id}}).Decode(&user)
+	// Credentials never leave the service, Password is still decoded from request bodies
+	opts := options.FindOne().SetProjection(bson.D{{"password", 0}, {"verificationCode", 0}, {"mfa", 0}, {"identities", 0}})
+	err := u.userCollection.FindOne(context.Background(), bson.D{{"_id", _id}}, opts).Decode(&user)
 	if err != nil {
 		return models.User{}, err
 	}
@@ -135,7 +148,27 @@ func (u *UserService) DeleteUserByID(careerID string) http.Response {
 	}
 }
 
+// SuspendUser blocks a career from signing in until it is restored
+func (u *UserService) SuspendUser(careerID string, reason string) error {
+	return suspendAccount(u.userCollection, careerID, reason)
+}
+
+// RestoreUser lifts a suspension or undoes a deletion
+func (u *UserService) RestoreUser(careerID string) error {
+	return restoreAccount(u.userCollection, careerID)
+}
+
 func (u *UserService) CreateUser(user models.User) error {
+	hashedPassword, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return fmt.Errorf("Error hashing password: %v", err)
+	}
+	user.Password = hashedPassword
+	if user.Id.IsZero() {
+		user.Id = primitive.NewObjectID()
+	}
+	user.IsVerified = false
+
 	u.uow.RegisterChange(func(ctx mongo.SessionContext) error {
 		filter := bson.M{"careerEmail": user.CareerEmail}
 		count, err := u.userCollection.CountDocuments(ctx, filter)
@@ -158,6 +191,11 @@ func (u *UserService) CreateUser(user models.User) error {
 	if err := u.uow.Commit(); err != nil {
 		return err
 	}
+
+	// The account exists even if the mail fails, the user can ask for a new link
+	if err := u.verification.SendVerificationEmail(constants.CAREER, user.Id, user.CareerEmail); err != nil {
+		log.Printf("Error sending verification email to %s: %v", user.CareerEmail, err)
+	}
 	return nil
 }
 
@@ -187,6 +225,20 @@ func (u *UserService) UpdateUserByID(userID string, updatedUser models.User) (mo
 		},
 	}
 
+	// A new email has to be verified again before the account can sign in with it
+	emailChanged := !strings.EqualFold(updatedUser.CareerEmail, existingUser.CareerEmail)
+	if emailChanged {
+		taken, err := u.emailTaken(updatedUser.CareerEmail, _id)
+		if err != nil {
+			return models.User{}, err
+		}
+		if taken {
+			return models.User{}, ErrEmailTaken
+		}
+		update["$set"].(bson.M)["isVerified"] = false
+		update["$unset"] = bson.M{"verifiedAt": ""}
+	}
+
 	result, err := u.userCollection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
 		return models.User{}, fmt.Errorf("error updating user: %v", err)
@@ -196,9 +248,26 @@ func (u *UserService) UpdateUserByID(userID string, updatedUser models.User) (mo
 		return models.User{}, fmt.Errorf("no changes were made to the user with ID %s", userID)
 	}
 
+	if emailChanged {
+		if err := u.verification.SendVerificationEmail(constants.CAREER, _id, updatedUser.CareerEmail); err != nil {
+			log.Printf("Error sending verification email to %s: %v", updatedUser.CareerEmail, err)
+		}
+	}
+
+	updatedUser.Password = ""
 	return updatedUser, nil
 }
 
+// emailTaken reports whether another live career signs in with the email
+func (u *UserService) emailTaken(email string, except primitive.ObjectID) (bool, error) {
+	count, err := u.userCollection.CountDocuments(context.Background(), bson.M{
+		"_id":         bson.M{"$ne": except},
+		"careerEmail": bson.M{"$regex": "^" + regexp.QuoteMeta(email) + "$", "$options": "i"},
+		"isDeleted":   false,
+	})
+	return count > 0, err
+}
+
 func (u *UserService) GetSavedJobByCareerID(careerID string) models.PaginateDocs[models.Jobs] {
 	var saveJob []models.Jobs
 	careerObjID, _ := primitive.ObjectIDFromHex(careerID)
@@ -288,57 +357,12 @@ func (u *UserService) RemoveResume(id string, link string) error {
 	return nil
 }
 
-func (u *UserService) RequestPasswordReset(email string) (string, error) {
-	var user models.User
-	err := u.userCollection.FindOne(context.Background(), bson.M{"careerEmail": email}).Decode(&user)
-	if err != nil {
-		return "", fmt.Errorf("Không tìm thấy người dùng với email %s: %v", email, err)
-	}
-
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	code := rng.Intn(9000) + 1000
-	verificationCode := fmt.Sprintf("%d", code)
-
-	subject := "Mã xác nhận khôi phục mật khẩu"
-	body := fmt.Sprintf("Mã xác nhận của bạn là: %s", verificationCode)
-	if err := SendEmail(email, subject, body); err != nil {
-		return "", fmt.Errorf("Lỗi khi gửi email: %v", err)
-	}
-
-	_, err = u.userCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": user.Id},
-		bson.M{"$set": bson.M{"verificationCode": verificationCode}},
-	)
-	if err != nil {
-		return "", fmt.Errorf("Lỗi khi tạo mã xác nhận: %v", err)
-	}
-
-	return verificationCode, nil
+func (u *UserService) RequestPasswordReset(email string, ip string) error {
+	return u.passwordReset.RequestReset(constants.CAREER, email, ip)
 }
 
 func (u *UserService) ResetPassword(email string, code string, newPassword string) error {
-	var user models.User
-
-	// Kiểm tra mã xác nhận
-	err := u.userCollection.FindOne(context.Background(), bson.M{"careerEmail": email, "verificationCode": code}).Decode(&user)
-	if err != nil {
-		return fmt.Errorf("Sai mã xác nhận hoặc email không hợp lệ: %v", err)
-	}
-	hashedPassword := utils.EncodeToSHA(newPassword)
-	_, err = u.userCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": user.Id},
-		bson.M{
-			"$set":   bson.M{"password": hashedPassword},
-			"$unset": bson.M{"verificationCode": ""},
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("Lỗi khi cập nhật mật khẩu mới: %v", err)
-	}
-
-	return nil
+	return u.passwordReset.ResetPassword(constants.CAREER, email, code, newPassword)
 }
 
 func (u *UserService) GetAppliedJob(id string, page int, pageSize int) ([]bson.M, error) {
@@ -386,8 +410,10 @@ func (u *UserService) GetAppliedJob(id string, page int, pageSize int) ([]bson.M
 			{"jobTitle", "$jobDetails.jobTitle"},
 			{"jobID", "$jobDetails._id"},
 			{"jobRequirement", "$jobDetails.jobRequirement"},
-			{"jobSalaryMin", "$jobDetails.jobSalaryMin"},
-			{"jobSalaryMax", "$jobDetails.jobSalaryMax"},
+			// Hidden salaries are not shown, even to applicants
+			{"jobSalaryMin", bson.D{{"$cond", bson.A{"$jobDetails.salary.hidden", "$$REMOVE", "$jobDetails.jobSalaryMin"}}}},
+			{"jobSalaryMax", bson.D{{"$cond", bson.A{"$jobDetails.salary.hidden", "$$REMOVE", "$jobDetails.jobSalaryMax"}}}},
+			{"salary", "$jobDetails.salary"},
 			{"companyImage", "$companyDetails.companyImage.imageURL"},
 			{"companyName", "$companyDetails.companyName"},
 			{"isDeleted", 1},
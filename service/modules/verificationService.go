@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireforwork-server/config"
+	"hireforwork-server/constants"
+	"hireforwork-server/db"
+	"hireforwork-server/service/modules/ratelimit"
+	"hireforwork-server/utils"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const verifyEmailPurpose = "verify-email"
+
+var (
+	ErrInvalidVerificationLink = errors.New("Liên kết xác thực không hợp lệ hoặc đã hết hạn")
+	ErrVerificationThrottled   = errors.New("Bạn đã yêu cầu gửi lại quá nhiều lần, vui lòng thử lại sau")
+	ErrEmailTaken              = errors.New("Email này đã được sử dụng")
+)
+
+var backfillOnce sync.Once
+
+type VerificationService struct {
+	careerCollection  *mongo.Collection
+	companyCollection *mongo.Collection
+	limiter           *ratelimit.Limiter
+	secret            []byte
+	ttl               time.Duration
+	baseURL           string
+}
+
+func NewVerificationService(dbInstance *db.DB) *VerificationService {
+	collections := dbInstance.GetCollections([]string{"Career", "Company"})
+	cfg := config.GetInstance()
+
+	verificationService := &VerificationService{
+		careerCollection:  collections[0],
+		companyCollection: collections[1],
+		limiter:           ratelimit.NewLimiter(dbInstance),
+		secret:            []byte(cfg.SecretKey),
+		ttl:               cfg.VerificationTTL,
+		baseURL:           cfg.AppBaseURL,
+	}
+	backfillOnce.Do(verificationService.backfillVerified)
+
+	return verificationService
+}
+
+// backfillVerified marks accounts created before email verification existed as verified
+func (v *VerificationService) backfillVerified() {
+	filter := bson.M{"isVerified": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"isVerified": true}}
+
+	for _, collection := range []*mongo.Collection{v.careerCollection, v.companyCollection} {
+		if _, err := collection.UpdateMany(context.Background(), filter, update); err != nil {
+			log.Printf("Error backfilling isVerified on %s: %v", collection.Name(), err)
+		}
+	}
+}
+
+func (v *VerificationService) collection(accountType string) *mongo.Collection {
+	if accountType == constants.COMPANY {
+		return v.companyCollection
+	}
+	return v.careerCollection
+}
+
+func emailField(accountType string) string {
+	if accountType == constants.COMPANY {
+		return "contact.companyEmail"
+	}
+	return "careerEmail"
+}
+
+func verifyPath(accountType string) string {
+	if accountType == constants.COMPANY {
+		return "/companies/verify-email"
+	}
+	return "/careers/verify-email"
+}
+
+// SendVerificationEmail mails a signed link that proves ownership of the address. The
+// link names the address, it stops working once the account changes its email.
+func (v *VerificationService) SendVerificationEmail(accountType string, id primitive.ObjectID, email string) error {
+	token := utils.SignToken(v.secret, verifyEmailPurpose, accountType+":"+id.Hex()+":"+email, v.ttl)
+	link := fmt.Sprintf("%s%s?token=%s", v.baseURL, verifyPath(accountType), token)
+
+	subject := "Xác thực địa chỉ email"
+	body := fmt.Sprintf(`Vui lòng bấm vào <a href="%s">liên kết này</a> để xác thực email. Liên kết có hiệu lực trong %s.`, link, v.ttl)
+	if err := SendEmail(email, subject, body); err != nil {
+		return fmt.Errorf("Lỗi khi gửi email: %v", err)
+	}
+	return nil
+}
+
+// VerifyEmail validates a link for the given account type and marks the account verified
+func (v *VerificationService) VerifyEmail(accountType string, token string) error {
+	subject, err := utils.VerifySignedToken(v.secret, token, verifyEmailPurpose)
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+
+	parts := strings.SplitN(subject, ":", 3)
+	if len(parts) != 3 || parts[0] != accountType {
+		return ErrInvalidVerificationLink
+	}
+	_id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return ErrInvalidVerificationLink
+	}
+
+	result, err := v.collection(accountType).UpdateOne(
+		context.Background(),
+		bson.M{"_id": _id, "isDeleted": false, emailField(accountType): parts[2]},
+		bson.M{"$set": bson.M{
+			"isVerified": true,
+			"verifiedAt": primitive.NewDateTimeFromTime(time.Now()),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvalidVerificationLink
+	}
+	return nil
+}
+
+// ResendVerification sends a new link, throttled per email and per client IP.
+// Unknown or already verified emails are ignored so the response does not reveal them.
+func (v *VerificationService) ResendVerification(accountType string, email string, ip string) error {
+	email = strings.TrimSpace(email)
+	if allowed, _ := v.limiter.Allow("verify:email:"+strings.ToLower(email), 3, time.Hour); !allowed {
+		return ErrVerificationThrottled
+	}
+	if allowed, _ := v.limiter.Allow("verify:ip:"+ip, 10, time.Hour); !allowed {
+		return ErrVerificationThrottled
+	}
+
+	var account struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	err := v.collection(accountType).FindOne(context.Background(), bson.M{
+		emailField(accountType): email,
+		"isDeleted":             false,
+		"isVerified":            false,
+	}).Decode(&account)
+	if err != nil {
+		return nil
+	}
+
+	return v.SendVerificationEmail(accountType, account.Id, email)
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hireforwork-server/config"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+1. Strategy pattern - PasswordHasher is swappable, the algorithm is picked from config
+2. Encoded hashes carry their own salt and parameters, old hashes keep verifying
+*/
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(encodedHash, password string) (bool, error)
+	NeedsRehash(encodedHash string) bool
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var ErrInvalidHash = errors.New("invalid password hash format")
+
+var legacySHAHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Argon2idHasher encodes hashes in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.Memory,
+		h.Iterations,
+		h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(encodedHash, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+func decodeArgon2id(encodedHash string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, key, nil
+}
+
+// BcryptHasher stores the salt and cost inside the standard $2a$ hash
+type BcryptHasher struct {
+	Cost int
+}
+
+func NewBcryptHasher() *BcryptHasher {
+	return &BcryptHasher{Cost: 12}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(encodedHash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return true
+	}
+	return cost != h.Cost
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// IsLegacySHAHash reports whether the hash was produced by EncodeToSHA
+func IsLegacySHAHash(encodedHash string) bool {
+	return legacySHAHash.MatchString(encodedHash)
+}
+
+func NewPasswordHasher(algorithm string) PasswordHasher {
+	switch strings.ToLower(algorithm) {
+	case "bcrypt":
+		return NewBcryptHasher()
+	default:
+		return NewArgon2idHasher()
+	}
+}
+
+var hasherInstance PasswordHasher
+var hasherOnce sync.Once
+
+// GetPasswordHasher returns the hasher configured by PASSWORD_HASHER
+func GetPasswordHasher() PasswordHasher {
+	hasherOnce.Do(func() {
+		hasherInstance = NewPasswordHasher(config.GetInstance().PasswordHasher)
+	})
+	return hasherInstance
+}
+
+func HashPassword(password string) (string, error) {
+	return GetPasswordHasher().Hash(password)
+}
+
+// VerifyPassword checks the password against a hash of any supported format and
+// reports whether the hash should be upgraded to the configured hasher.
+func VerifyPassword(encodedHash, password string) (match bool, needsRehash bool) {
+	var err error
+
+	switch {
+	case strings.HasPrefix(encodedHash, argon2idPrefix):
+		match, err = (&Argon2idHasher{}).Verify(encodedHash, password)
+	case isBcryptHash(encodedHash):
+		match, err = (&BcryptHasher{}).Verify(encodedHash, password)
+	case IsLegacySHAHash(encodedHash):
+		match = subtle.ConstantTimeCompare([]byte(encodedHash), []byte(EncodeToSHA(password))) == 1
+	default:
+		return false, false
+	}
+
+	if err != nil || !match {
+		return false, false
+	}
+	return true, GetPasswordHasher().NeedsRehash(encodedHash)
+}
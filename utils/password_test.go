@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	// Argon2id reference implementation test vector: "password", salt "somesalt",
+	// t=2, m=64 MiB, p=1
+	knownArgon2idHash = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	// OpenBSD bcrypt test vector for "U*U" at cost 5
+	knownBcryptHash = "$2a$05$CCCCCCCCCCCCCCCCCCCCC.E5YPO9kmyuRGyh0XouQYb4YMJKvyOeW"
+	// SHA3-256 of "abc", the format of EncodeToSHA
+	knownLegacyHash = "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	tests := []struct {
+		name            string
+		hash            string
+		password        string
+		wantMatch       bool
+		wantNeedsRehash bool
+	}{
+		{"argon2id known answer", knownArgon2idHash, "password", true, true},
+		{"argon2id wrong password", knownArgon2idHash, "Password", false, false},
+		{"argon2id tampered key", strings.Replace(knownArgon2idHash, "$CTFhFd", "$CTFhFe", 1), "password", false, false},
+		{"argon2id tampered salt", strings.Replace(knownArgon2idHash, "c29tZXNhbHQ", "c29tZXNhbXQ", 1), "password", false, false},
+		{"argon2id tampered parameters", strings.Replace(knownArgon2idHash, "t=2", "t=3", 1), "password", false, false},
+		{"argon2id other version", strings.Replace(knownArgon2idHash, "v=19", "v=16", 1), "password", false, false},
+		{"argon2id truncated", knownArgon2idHash[:40], "password", false, false},
+		{"bcrypt known answer", knownBcryptHash, "U*U", true, true},
+		{"bcrypt wrong password", knownBcryptHash, "U*V", false, false},
+		{"bcrypt tampered", strings.Replace(knownBcryptHash, "E5YPO9", "E5YPO8", 1), "U*U", false, false},
+		{"legacy sha3 known answer", knownLegacyHash, "abc", true, true},
+		{"legacy sha3 wrong password", knownLegacyHash, "abd", false, false},
+		{"unknown format", "plaintext", "plaintext", false, false},
+		{"empty hash", "", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, needsRehash := VerifyPassword(tt.hash, tt.password)
+			if match != tt.wantMatch || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("VerifyPassword() = (%v, %v), want (%v, %v)", match, needsRehash, tt.wantMatch, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		prefix string
+	}{
+		{"argon2id", NewArgon2idHasher(), "$argon2id$v=19$m=65536,t=3,p=2$"},
+		{"bcrypt", &BcryptHasher{Cost: 4}, "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("Hash() = %q, want prefix %q", hash, tt.prefix)
+			}
+
+			if ok, err := tt.hasher.Verify(hash, "correct horse battery staple"); !ok || err != nil {
+				t.Errorf("Verify() = (%v, %v) for the hashed password", ok, err)
+			}
+			if ok, _ := tt.hasher.Verify(hash, "correct horse battery stapler"); ok {
+				t.Error("Verify() accepted another password")
+			}
+			if tt.hasher.NeedsRehash(hash) {
+				t.Error("NeedsRehash() on a hash of the same hasher")
+			}
+
+			// A fresh salt every time
+			other, _ := tt.hasher.Hash("correct horse battery staple")
+			if other == hash {
+				t.Error("Hash() returned the same hash twice")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		hash   string
+		want   bool
+	}{
+		{"argon2id weaker parameters", NewArgon2idHasher(), knownArgon2idHash, true},
+		{"argon2id from bcrypt", NewArgon2idHasher(), knownBcryptHash, true},
+		{"bcrypt other cost", NewBcryptHasher(), knownBcryptHash, true},
+		{"bcrypt same cost", &BcryptHasher{Cost: 5}, knownBcryptHash, false},
+		{"bcrypt from argon2id", NewBcryptHasher(), knownArgon2idHash, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLegacySHAHash(t *testing.T) {
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{knownLegacyHash, true},
+		{EncodeToSHA("password"), true},
+		{strings.ToUpper(knownLegacyHash), false},
+		{knownLegacyHash[:63], false},
+		{knownArgon2idHash, false},
+		{knownBcryptHash, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLegacySHAHash(tt.hash); got != tt.want {
+			t.Errorf("IsLegacySHAHash(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
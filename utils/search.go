@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// vietnameseFolds lists every accented Vietnamese letter under its plain form
+var vietnameseFolds = map[rune]string{
+	'a': "àáảãạăằắẳẵặâầấẩẫậ",
+	'd': "đ",
+	'e': "èéẻẽẹêềếểễệ",
+	'i': "ìíỉĩị",
+	'o': "òóỏõọôồốổỗộơờớởỡợ",
+	'u': "ùúủũụưừứửữự",
+	'y': "ỳýỷỹỵ",
+}
+
+var foldTable = func() map[rune]rune {
+	table := map[rune]rune{}
+	for plain, accented := range vietnameseFolds {
+		for _, r := range accented {
+			table[r] = plain
+		}
+	}
+	return table
+}()
+
+// FoldText lowercases s, strips Vietnamese diacritics ("Đà Nẵng" becomes "da nang")
+// and collapses whitespace, so searches match however the text was typed
+func FoldText(s string) string {
+	folded := strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if plain, ok := foldTable[r]; ok {
+			return plain
+		}
+		// Combining marks left by decomposed input
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(folded), " ")
+}
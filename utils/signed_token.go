@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignedToken = errors.New("invalid signed token")
+	ErrExpiredSignedToken = errors.New("signed token has expired")
+)
+
+// SignToken builds a stateless "<payload>.<signature>" token binding a subject to a
+// purpose until ttl elapses, used for links sent by email
+func SignToken(secret []byte, purpose, subject string, ttl time.Duration) string {
+	payload := fmt.Sprintf("%s|%s|%d", purpose, subject, time.Now().Add(ttl).Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + sign(secret, encoded)
+}
+
+// VerifySignedToken checks the signature, purpose and expiry and returns the subject
+func VerifySignedToken(secret []byte, token, purpose string) (string, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(sign(secret, encoded))) {
+		return "", ErrInvalidSignedToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidSignedToken
+	}
+
+	// The subject sits between the purpose and the expiry, it may contain "|" itself
+	// (an email can)
+	tokenPurpose, rest, found := strings.Cut(string(payload), "|")
+	separator := strings.LastIndex(rest, "|")
+	if !found || separator < 0 || tokenPurpose != purpose {
+		return "", ErrInvalidSignedToken
+	}
+	subject, expiry := rest[:separator], rest[separator+1:]
+
+	expireAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignedToken
+	}
+	if time.Now().Unix() > expireAt {
+		return "", ErrExpiredSignedToken
+	}
+
+	return subject, nil
+}
+
+func sign(secret []byte, data string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// RFC 4231 test case 2, base64url without padding
+	if got := sign([]byte("Jefe"), "what do ya want for nothing?"); got != "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM" {
+		t.Errorf("sign() = %q", got)
+	}
+}
+
+func TestVerifySignedToken(t *testing.T) {
+	secret := []byte("secret")
+	valid := SignToken(secret, "verify-email", "CAREER:5f1d7c1e8a1b2c3d4e5f6a7b:an@example.com", time.Hour)
+	encoded, signature, _ := strings.Cut(valid, ".")
+	otherEncoded, _, _ := strings.Cut(SignToken(secret, "verify-email", "CAREER:5f1d7c1e8a1b2c3d4e5f6a7c:an@example.com", time.Hour), ".")
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		token   string
+		purpose string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "signed now",
+			secret:  secret,
+			token:   valid,
+			purpose: "verify-email",
+			want:    "CAREER:5f1d7c1e8a1b2c3d4e5f6a7b:an@example.com",
+		},
+		{
+			// "verify-email|CAREER:5f1d7c1e8a1b2c3d4e5f6a7b:an|nguyen@example.com|4102444800"
+			name:    "known token with | in the subject",
+			secret:  secret,
+			token:   "dmVyaWZ5LWVtYWlsfENBUkVFUjo1ZjFkN2MxZThhMWIyYzNkNGU1ZjZhN2I6YW58bmd1eWVuQGV4YW1wbGUuY29tfDQxMDI0NDQ4MDA.4YytHjIwtIhuFDV0Q2PWXuiF6-2Q8rQZB5w3EmWnGco",
+			purpose: "verify-email",
+			want:    "CAREER:5f1d7c1e8a1b2c3d4e5f6a7b:an|nguyen@example.com",
+		},
+		{
+			// "verify-email|CAREER:5f1d7c1e8a1b2c3d4e5f6a7b|946684800", expired in 2000
+			name:    "known expired token",
+			secret:  secret,
+			token:   "dmVyaWZ5LWVtYWlsfENBUkVFUjo1ZjFkN2MxZThhMWIyYzNkNGU1ZjZhN2J8OTQ2Njg0ODAw.WpDFMARUpp_fs262uvRldmuwUoUiXakvUr_-lEaAEz4",
+			purpose: "verify-email",
+			wantErr: ErrExpiredSignedToken,
+		},
+		{
+			name:    "expired",
+			secret:  secret,
+			token:   SignToken(secret, "verify-email", "CAREER:1", -time.Second),
+			purpose: "verify-email",
+			wantErr: ErrExpiredSignedToken,
+		},
+		{
+			name:    "other purpose",
+			secret:  secret,
+			token:   valid,
+			purpose: "password-reset",
+			wantErr: ErrInvalidSignedToken,
+		},
+		{
+			name:    "other secret",
+			secret:  []byte("another secret"),
+			token:   valid,
+			purpose: "verify-email",
+			wantErr: ErrInvalidSignedToken,
+		},
+		{
+			name:    "tampered payload",
+			secret:  secret,
+			token:   otherEncoded + "." + signature,
+			purpose: "verify-email",
+			wantErr: ErrInvalidSignedToken,
+		},
+		{
+			name:    "tampered signature",
+			secret:  secret,
+			token:   encoded + "." + strings.ToUpper(signature),
+			purpose: "verify-email",
+			wantErr: ErrInvalidSignedToken,
+		},
+		{
+			name:    "no signature",
+			secret:  secret,
+			token:   encoded,
+			purpose: "verify-email",
+			wantErr: ErrInvalidSignedToken,
+		},
+		{
+			name:    "empty",
+			secret:  secret,
+			token:   "",
+			purpose: "verify-email",
+			wantErr: ErrInvalidSignedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifySignedToken(tt.secret, tt.token, tt.purpose)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifySignedToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifySignedToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
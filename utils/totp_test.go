@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// base32 of the ASCII secret "12345678901234567890" used by RFC 4226 and RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	key := []byte("12345678901234567890")
+	for counter, code := range want {
+		if got := hotp(key, int64(counter)); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		at       int64
+		wantStep int64
+		wantOK   bool
+	}{
+		// RFC 6238 appendix B SHA1 vectors, last six of the eight digits
+		{"rfc 59", rfcSecret, "287082", 59, 1, true},
+		{"rfc 1111111109", rfcSecret, "081804", 1111111109, 37037036, true},
+		{"rfc 1111111111", rfcSecret, "050471", 1111111111, 37037037, true},
+		{"rfc 1234567890", rfcSecret, "005924", 1234567890, 41152263, true},
+		{"rfc 2000000000", rfcSecret, "279037", 2000000000, 66666666, true},
+		{"rfc 20000000000", rfcSecret, "353130", 20000000000, 666666666, true},
+		{"lowercase secret", strings.ToLower(rfcSecret), "287082", 59, 1, true},
+		{"previous step", rfcSecret, "287082", 59 + 30, 1, true},
+		{"next step", rfcSecret, "287082", 59 - 30, 1, true},
+		{"two steps late", rfcSecret, "287082", 59 + 60, 0, false},
+		{"wrong code", rfcSecret, "287083", 59, 0, false},
+		{"eight digits", rfcSecret, "94287082", 59, 0, false},
+		{"empty code", rfcSecret, "", 59, 0, false},
+		{"invalid secret", "not base32!", "287082", 59, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.at, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP() = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("GenerateTOTPSecret() = %q, want 160 bits of base32", secret)
+	}
+
+	now := time.Now()
+	if _, ok := ValidateTOTP(secret, hotp(key, TOTPStep(now)), now); !ok {
+		t.Error("the current code of a generated secret is refused")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("HireForWork", "an@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/HireForWork:an@example.com" {
+		t.Errorf("TOTPProvisioningURI() = %s", uri)
+	}
+
+	want := map[string]string{"secret": rfcSecret, "issuer": "HireForWork", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for param, value := range want {
+		if got := uri.Query().Get(param); got != value {
+			t.Errorf("%s = %q, want %q", param, got, value)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"hireforwork-server/config"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	cfg := config.GetInstance()
+	previous := cfg.TrustedProxies
+	t.Cleanup(func() { cfg.TrustedProxies = previous })
+
+	proxies := []*net.IPNet{}
+	for _, cidr := range []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::/8"} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxies = append(proxies, network)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    []*net.IPNet
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"no proxy configured ignores the header", nil, "203.0.113.7:4321", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"untrusted peer ignores the header", proxies, "203.0.113.7:4321", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy without header", proxies, "10.0.0.2:4321", nil, "10.0.0.2"},
+		{"trusted proxy", proxies, "10.0.0.2:4321", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", proxies, "10.0.0.2:4321", []string{"198.51.100.1, 192.168.1.1, 10.1.2.3"}, "198.51.100.1"},
+		{"spoofed entries left of the client", proxies, "10.0.0.2:4321", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"header split over several lines", proxies, "10.0.0.2:4321", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"garbage stops the walk", proxies, "10.0.0.2:4321", []string{"198.51.100.1, not-an-ip"}, "10.0.0.2"},
+		{"every hop trusted", proxies, "10.0.0.2:4321", []string{"10.9.9.9"}, "10.9.9.9"},
+		{"ipv6 proxy", proxies, "[fd00::1]:4321", []string{"2001:db8::5"}, "2001:db8::5"},
+		{"remote address without port", nil, "203.0.113.7", nil, "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.TrustedProxies = tt.proxies
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}